@@ -0,0 +1,526 @@
+package client
+
+import (
+	"encoding/json"
+)
+
+// Aggregation is a node of the aggregations DSL. Source returns its body as
+// it appears under the aggregation name, e.g. {"terms": {"field": "meal"}}.
+// Attach aggregations to a search with WithAggregation and read the results
+// from SearchResult.Aggregations.
+type Aggregation interface {
+	Source() map[string]interface{}
+}
+
+// WithAggregation computes a under name alongside the hits. Use WithSize(0)
+// when only the aggregations are needed.
+func WithAggregation(name string, a Aggregation) SearchOption {
+	return func(o *searchOptions) {
+		aggs, _ := o.body["aggs"].(map[string]interface{})
+		if aggs == nil {
+			aggs = map[string]interface{}{}
+			o.set("aggs", aggs)
+		}
+		aggs[name] = a.Source()
+	}
+}
+
+// subAggs holds the sub-aggregations of a bucket aggregation.
+type subAggs map[string]Aggregation
+
+func (s subAggs) source(typ string, body map[string]interface{}) map[string]interface{} {
+	m := map[string]interface{}{typ: body}
+	if len(s) > 0 {
+		aggs := make(map[string]interface{}, len(s))
+		for name, a := range s {
+			aggs[name] = a.Source()
+		}
+		m["aggs"] = aggs
+	}
+	return m
+}
+
+func (s *subAggs) add(name string, a Aggregation) {
+	if *s == nil {
+		*s = subAggs{}
+	}
+	(*s)[name] = a
+}
+
+// TermsAggregation buckets documents by the distinct values of a field.
+type TermsAggregation struct {
+	field       string
+	size        *int
+	minDocCount *int
+	order       map[string]string
+	missing     interface{}
+	aggs        subAggs
+}
+
+func NewTermsAggregation(field string) *TermsAggregation {
+	return &TermsAggregation{field: field}
+}
+
+// Size is the number of buckets returned, 10 by default.
+func (a *TermsAggregation) Size(n int) *TermsAggregation {
+	a.size = &n
+	return a
+}
+
+func (a *TermsAggregation) MinDocCount(n int) *TermsAggregation {
+	a.minDocCount = &n
+	return a
+}
+
+// Order sorts the buckets by key ("_key"), count ("_count") or a metric
+// sub-aggregation name, with direction "asc" or "desc".
+func (a *TermsAggregation) Order(key, direction string) *TermsAggregation {
+	a.order = map[string]string{key: direction}
+	return a
+}
+
+// Missing buckets documents without the field under value.
+func (a *TermsAggregation) Missing(value interface{}) *TermsAggregation {
+	a.missing = value
+	return a
+}
+
+func (a *TermsAggregation) SubAggregation(name string, sub Aggregation) *TermsAggregation {
+	a.aggs.add(name, sub)
+	return a
+}
+
+func (a *TermsAggregation) Source() map[string]interface{} {
+	b := map[string]interface{}{"field": a.field}
+	if a.size != nil {
+		b["size"] = *a.size
+	}
+	if a.minDocCount != nil {
+		b["min_doc_count"] = *a.minDocCount
+	}
+	if a.order != nil {
+		b["order"] = a.order
+	}
+	if a.missing != nil {
+		b["missing"] = a.missing
+	}
+	return a.aggs.source("terms", b)
+}
+
+// HistogramAggregation buckets numeric values into fixed size intervals.
+type HistogramAggregation struct {
+	field       string
+	interval    float64
+	minDocCount *int
+	aggs        subAggs
+}
+
+func NewHistogramAggregation(field string, interval float64) *HistogramAggregation {
+	return &HistogramAggregation{field: field, interval: interval}
+}
+
+func (a *HistogramAggregation) MinDocCount(n int) *HistogramAggregation {
+	a.minDocCount = &n
+	return a
+}
+
+func (a *HistogramAggregation) SubAggregation(name string, sub Aggregation) *HistogramAggregation {
+	a.aggs.add(name, sub)
+	return a
+}
+
+func (a *HistogramAggregation) Source() map[string]interface{} {
+	b := map[string]interface{}{"field": a.field, "interval": a.interval}
+	if a.minDocCount != nil {
+		b["min_doc_count"] = *a.minDocCount
+	}
+	return a.aggs.source("histogram", b)
+}
+
+// DateHistogramAggregation buckets dates into calendar or fixed intervals.
+type DateHistogramAggregation struct {
+	field            string
+	calendarInterval string
+	fixedInterval    string
+	format           string
+	timeZone         string
+	minDocCount      *int
+	aggs             subAggs
+}
+
+func NewDateHistogramAggregation(field string) *DateHistogramAggregation {
+	return &DateHistogramAggregation{field: field}
+}
+
+// CalendarInterval is a calendar unit such as "day", "week" or "month".
+func (a *DateHistogramAggregation) CalendarInterval(i string) *DateHistogramAggregation {
+	a.calendarInterval = i
+	return a
+}
+
+// FixedInterval is a duration such as "12h" or "30d".
+func (a *DateHistogramAggregation) FixedInterval(i string) *DateHistogramAggregation {
+	a.fixedInterval = i
+	return a
+}
+
+// Format sets the date format of the bucket KeyAsString.
+func (a *DateHistogramAggregation) Format(f string) *DateHistogramAggregation {
+	a.format = f
+	return a
+}
+
+func (a *DateHistogramAggregation) TimeZone(tz string) *DateHistogramAggregation {
+	a.timeZone = tz
+	return a
+}
+
+func (a *DateHistogramAggregation) MinDocCount(n int) *DateHistogramAggregation {
+	a.minDocCount = &n
+	return a
+}
+
+func (a *DateHistogramAggregation) SubAggregation(name string, sub Aggregation) *DateHistogramAggregation {
+	a.aggs.add(name, sub)
+	return a
+}
+
+func (a *DateHistogramAggregation) Source() map[string]interface{} {
+	b := map[string]interface{}{"field": a.field}
+	if a.calendarInterval != "" {
+		b["calendar_interval"] = a.calendarInterval
+	}
+	if a.fixedInterval != "" {
+		b["fixed_interval"] = a.fixedInterval
+	}
+	if a.format != "" {
+		b["format"] = a.format
+	}
+	if a.timeZone != "" {
+		b["time_zone"] = a.timeZone
+	}
+	if a.minDocCount != nil {
+		b["min_doc_count"] = *a.minDocCount
+	}
+	return a.aggs.source("date_histogram", b)
+}
+
+// RangeAggregation buckets numeric values into the given ranges. From is
+// inclusive and To exclusive; nil leaves a side open.
+type RangeAggregation struct {
+	field  string
+	ranges []map[string]interface{}
+	aggs   subAggs
+}
+
+func NewRangeAggregation(field string) *RangeAggregation {
+	return &RangeAggregation{field: field}
+}
+
+// AddRange adds the bucket [from, to). key names the bucket and may be empty.
+func (a *RangeAggregation) AddRange(key string, from, to interface{}) *RangeAggregation {
+	r := map[string]interface{}{}
+	if key != "" {
+		r["key"] = key
+	}
+	if from != nil {
+		r["from"] = from
+	}
+	if to != nil {
+		r["to"] = to
+	}
+	a.ranges = append(a.ranges, r)
+	return a
+}
+
+func (a *RangeAggregation) SubAggregation(name string, sub Aggregation) *RangeAggregation {
+	a.aggs.add(name, sub)
+	return a
+}
+
+func (a *RangeAggregation) Source() map[string]interface{} {
+	ranges := a.ranges
+	if ranges == nil {
+		ranges = []map[string]interface{}{}
+	}
+	return a.aggs.source("range", map[string]interface{}{"field": a.field, "ranges": ranges})
+}
+
+// metricAggregation is a single field metric: stats, cardinality, avg, ...
+type metricAggregation struct {
+	typ   string
+	field string
+}
+
+func (a *metricAggregation) Source() map[string]interface{} {
+	return map[string]interface{}{a.typ: map[string]interface{}{"field": a.field}}
+}
+
+// NewStatsAggregation computes count, min, max, avg and sum of a field.
+func NewStatsAggregation(field string) Aggregation {
+	return &metricAggregation{typ: "stats", field: field}
+}
+
+// NewCardinalityAggregation approximates the number of distinct values of a
+// field.
+func NewCardinalityAggregation(field string) Aggregation {
+	return &metricAggregation{typ: "cardinality", field: field}
+}
+
+// NewAvgAggregation, NewMinAggregation, NewMaxAggregation and
+// NewSumAggregation compute a single value of a field.
+func NewAvgAggregation(field string) Aggregation { return &metricAggregation{typ: "avg", field: field} }
+func NewMinAggregation(field string) Aggregation { return &metricAggregation{typ: "min", field: field} }
+func NewMaxAggregation(field string) Aggregation { return &metricAggregation{typ: "max", field: field} }
+func NewSumAggregation(field string) Aggregation { return &metricAggregation{typ: "sum", field: field} }
+
+// PercentilesAggregation estimates percentiles of a field.
+type PercentilesAggregation struct {
+	field    string
+	percents []float64
+}
+
+func NewPercentilesAggregation(field string, percents ...float64) *PercentilesAggregation {
+	return &PercentilesAggregation{field: field, percents: percents}
+}
+
+func (a *PercentilesAggregation) Source() map[string]interface{} {
+	b := map[string]interface{}{"field": a.field}
+	if len(a.percents) > 0 {
+		b["percents"] = a.percents
+	}
+	return map[string]interface{}{"percentiles": b}
+}
+
+// TopHitsAggregation returns the best matching hits of each bucket.
+type TopHitsAggregation struct {
+	size     *int
+	includes []string
+}
+
+func NewTopHitsAggregation() *TopHitsAggregation {
+	return &TopHitsAggregation{}
+}
+
+func (a *TopHitsAggregation) Size(n int) *TopHitsAggregation {
+	a.size = &n
+	return a
+}
+
+// SourceIncludes limits the _source of the returned hits.
+func (a *TopHitsAggregation) SourceIncludes(fields ...string) *TopHitsAggregation {
+	a.includes = fields
+	return a
+}
+
+func (a *TopHitsAggregation) Source() map[string]interface{} {
+	b := map[string]interface{}{}
+	if a.size != nil {
+		b["size"] = *a.size
+	}
+	if a.includes != nil {
+		b["_source"] = map[string]interface{}{"includes": a.includes}
+	}
+	return map[string]interface{}{"top_hits": b}
+}
+
+// NestedAggregation aggregates over the nested objects under path.
+type NestedAggregation struct {
+	path string
+	aggs subAggs
+}
+
+func NewNestedAggregation(path string) *NestedAggregation {
+	return &NestedAggregation{path: path}
+}
+
+func (a *NestedAggregation) SubAggregation(name string, sub Aggregation) *NestedAggregation {
+	a.aggs.add(name, sub)
+	return a
+}
+
+func (a *NestedAggregation) Source() map[string]interface{} {
+	return a.aggs.source("nested", map[string]interface{}{"path": a.path})
+}
+
+// FilterAggregation narrows its sub-aggregations to the documents matching
+// a query.
+type FilterAggregation struct {
+	query Query
+	aggs  subAggs
+}
+
+func NewFilterAggregation(q Query) *FilterAggregation {
+	return &FilterAggregation{query: q}
+}
+
+func (a *FilterAggregation) SubAggregation(name string, sub Aggregation) *FilterAggregation {
+	a.aggs.add(name, sub)
+	return a
+}
+
+func (a *FilterAggregation) Source() map[string]interface{} {
+	q := a.query
+	if q == nil {
+		q = NewMatchAllQuery()
+	}
+	return a.aggs.source("filter", q.Source())
+}
+
+// Aggregations holds the raw aggregation results of a search by name. The
+// typed accessors decode one of them and report false when it is missing
+// or has another shape.
+type Aggregations map[string]json.RawMessage
+
+func (a Aggregations) decode(name string, v interface{}) bool {
+	raw, ok := a[name]
+	if !ok {
+		return false
+	}
+	return json.Unmarshal(raw, v) == nil
+}
+
+// Buckets returns the result of a terms, histogram, date_histogram or range
+// aggregation.
+func (a Aggregations) Buckets(name string) (*BucketsResult, bool) {
+	var r BucketsResult
+	if !a.decode(name, &r) || r.Buckets == nil {
+		return nil, false
+	}
+	return &r, true
+}
+
+// Terms returns the result of a terms aggregation.
+func (a Aggregations) Terms(name string) (*BucketsResult, bool) { return a.Buckets(name) }
+
+// Histogram returns the result of a histogram or date_histogram aggregation.
+func (a Aggregations) Histogram(name string) (*BucketsResult, bool) { return a.Buckets(name) }
+
+// Range returns the result of a range aggregation.
+func (a Aggregations) Range(name string) (*BucketsResult, bool) { return a.Buckets(name) }
+
+// Stats returns the result of a stats aggregation.
+func (a Aggregations) Stats(name string) (*StatsResult, bool) {
+	var r StatsResult
+	if !a.decode(name, &r) {
+		return nil, false
+	}
+	return &r, true
+}
+
+// Value returns the result of a single value metric such as cardinality,
+// avg, min, max or sum.
+func (a Aggregations) Value(name string) (*ValueResult, bool) {
+	var r ValueResult
+	if !a.decode(name, &r) {
+		return nil, false
+	}
+	return &r, true
+}
+
+// Percentiles returns the result of a percentiles aggregation.
+func (a Aggregations) Percentiles(name string) (*PercentilesResult, bool) {
+	var r PercentilesResult
+	if !a.decode(name, &r) || r.Values == nil {
+		return nil, false
+	}
+	return &r, true
+}
+
+// TopHits returns the result of a top_hits aggregation.
+func (a Aggregations) TopHits(name string) (*TopHitsResult, bool) {
+	var r struct {
+		Hits *TopHitsResult `json:"hits"`
+	}
+	if !a.decode(name, &r) || r.Hits == nil {
+		return nil, false
+	}
+	return r.Hits, true
+}
+
+// SingleBucket returns the result of a nested or filter aggregation.
+func (a Aggregations) SingleBucket(name string) (*Bucket, bool) {
+	var r Bucket
+	if !a.decode(name, &r) {
+		return nil, false
+	}
+	return &r, true
+}
+
+// BucketsResult is the result of a multi bucket aggregation.
+type BucketsResult struct {
+	DocCountErrorUpperBound int64    `json:"doc_count_error_upper_bound"`
+	SumOtherDocCount        int64    `json:"sum_other_doc_count"`
+	Buckets                 []Bucket `json:"buckets"`
+}
+
+// Bucket is one bucket of a bucket aggregation. Its sub-aggregation results
+// are in Aggregations.
+type Bucket struct {
+	Key          interface{}
+	KeyAsString  string
+	DocCount     int64
+	From, To     *float64 // range buckets only
+	Aggregations Aggregations
+}
+
+func (b *Bucket) UnmarshalJSON(data []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	for k, v := range m {
+		var err error
+		switch k {
+		case "key":
+			err = json.Unmarshal(v, &b.Key)
+		case "key_as_string":
+			err = json.Unmarshal(v, &b.KeyAsString)
+		case "doc_count":
+			err = json.Unmarshal(v, &b.DocCount)
+		case "from":
+			err = json.Unmarshal(v, &b.From)
+		case "to":
+			err = json.Unmarshal(v, &b.To)
+		case "from_as_string", "to_as_string", "doc_count_error_upper_bound":
+		default:
+			if len(v) > 0 && v[0] == '{' {
+				if b.Aggregations == nil {
+					b.Aggregations = Aggregations{}
+				}
+				b.Aggregations[k] = v
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StatsResult is the result of a stats aggregation. The values are nil when
+// no document has the field.
+type StatsResult struct {
+	Count int64    `json:"count"`
+	Min   *float64 `json:"min"`
+	Max   *float64 `json:"max"`
+	Avg   *float64 `json:"avg"`
+	Sum   *float64 `json:"sum"`
+}
+
+// ValueResult is the result of a single value metric aggregation.
+type ValueResult struct {
+	Value *float64 `json:"value"`
+}
+
+// PercentilesResult maps each percentile, such as "99.0", to its value.
+type PercentilesResult struct {
+	Values map[string]*float64 `json:"values"`
+}
+
+// TopHitsResult is the result of a top_hits aggregation.
+type TopHitsResult struct {
+	Total    TotalHits `json:"total"`
+	MaxScore float32   `json:"max_score"`
+	Hits     []Hit     `json:"hits"`
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestAggregationsFacets(t *testing.T) {
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		var body struct {
+			Query json.RawMessage            `json:"query"`
+			Aggs  map[string]json.RawMessage `json:"aggs"`
+		}
+		if err := json.Unmarshal(b, &body); err != nil || body.Query == nil {
+			t.Errorf("bad body %s", b)
+		}
+		if string(body.Aggs["category"]) != `{"aggs":{"serves":{"stats":{"field":"serves"}}},"terms":{"field":"category","size":5}}` {
+			t.Errorf("unexpected category agg %s", body.Aggs["category"])
+		}
+		if string(body.Aggs["labels"]) != `{"cardinality":{"field":"labels"}}` {
+			t.Errorf("unexpected labels agg %s", body.Aggs["labels"])
+		}
+		w.Write([]byte(`{"took":1,"hits":{"total":{"value":3,"relation":"eq"},"hits":[]},"aggregations":{
+			"category":{"doc_count_error_upper_bound":0,"sum_other_doc_count":0,"buckets":[
+				{"key":"dessert","doc_count":2,"serves":{"count":2,"min":4,"max":8,"avg":6,"sum":12}},
+				{"key":"soup","doc_count":1,"serves":{"count":1,"min":2,"max":2,"avg":2,"sum":2}}]},
+			"labels":{"value":7}}}`))
+	})
+
+	res, err := s.Search("recipe_data", NewMatchQuery("title", "apple"),
+		WithSize(0),
+		WithAggregation("category", NewTermsAggregation("category").Size(5).
+			SubAggregation("serves", NewStatsAggregation("serves"))),
+		WithAggregation("labels", NewCardinalityAggregation("labels")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cat, ok := res.Aggregations.Terms("category")
+	if !ok || len(cat.Buckets) != 2 {
+		t.Fatalf("category buckets: %+v", cat)
+	}
+	if cat.Buckets[0].Key != "dessert" || cat.Buckets[0].DocCount != 2 {
+		t.Errorf("first bucket: %+v", cat.Buckets[0])
+	}
+	stats, ok := cat.Buckets[0].Aggregations.Stats("serves")
+	if !ok || stats.Count != 2 || *stats.Avg != 6 {
+		t.Errorf("serves stats: %+v", stats)
+	}
+	labels, ok := res.Aggregations.Value("labels")
+	if !ok || *labels.Value != 7 {
+		t.Errorf("labels cardinality: %+v", labels)
+	}
+	if _, ok := res.Aggregations.Terms("missing"); ok {
+		t.Error("expected no result for an unknown aggregation")
+	}
+}
@@ -0,0 +1,229 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// AliasAction is one step of an atomic alias update, see UpdateAliases.
+//
+// An alias is a second name for one or more indices. Every method of
+// SearchEngine taking an index name takes an alias as well: searches run on
+// all its indices, writes go to its write index.
+type AliasAction struct {
+	kind   string
+	params map[string]interface{}
+}
+
+// NewAddAlias points alias at indexName, or changes the filter, routing and
+// write flag of an existing alias on it.
+func NewAddAlias(indexName, alias string) *AliasAction {
+	return &AliasAction{kind: "add", params: map[string]interface{}{"index": indexName, "alias": alias}}
+}
+
+// NewRemoveAlias removes alias from indexName.
+func NewRemoveAlias(indexName, alias string) *AliasAction {
+	return &AliasAction{kind: "remove", params: map[string]interface{}{"index": indexName, "alias": alias}}
+}
+
+// NewRemoveIndex deletes indexName, so that an alias can take its name in
+// the same update.
+func NewRemoveIndex(indexName string) *AliasAction {
+	return &AliasAction{kind: "remove_index", params: map[string]interface{}{"index": indexName}}
+}
+
+// Filter limits what the alias sees to the documents matching q.
+func (a *AliasAction) Filter(q Query) *AliasAction {
+	a.params["filter"] = q.Source()
+	return a
+}
+
+// Routing routes the searches and writes through the alias to the shard of
+// the routing value r.
+func (a *AliasAction) Routing(r string) *AliasAction {
+	a.params["routing"] = r
+	return a
+}
+
+// IndexRouting overrides Routing for writes.
+func (a *AliasAction) IndexRouting(r string) *AliasAction {
+	a.params["index_routing"] = r
+	return a
+}
+
+// SearchRouting overrides Routing for searches; it may list several values
+// separated by commas.
+func (a *AliasAction) SearchRouting(r string) *AliasAction {
+	a.params["search_routing"] = r
+	return a
+}
+
+// WriteIndex makes indexName the index that writes through an alias on
+// several indices go to.
+func (a *AliasAction) WriteIndex(b bool) *AliasAction {
+	a.params["is_write_index"] = b
+	return a
+}
+
+func (a *AliasAction) Source() map[string]interface{} {
+	return map[string]interface{}{a.kind: a.params}
+}
+
+// Alias describes an alias on one index.
+type Alias struct {
+	Alias         string
+	Index         string
+	Filter        json.RawMessage // the filter query, if any
+	IndexRouting  string
+	SearchRouting string
+	IsWriteIndex  bool
+}
+
+// UpdateAliases applies actions in one atomic step: searches see either all
+// of them or none.
+func (r *SearchEngine) UpdateAliases(actions ...*AliasAction) error {
+	return r.UpdateAliasesCtx(context.Background(), actions...)
+}
+
+// UpdateAliasesCtx is like UpdateAliases but carries ctx to the request.
+func (r *SearchEngine) UpdateAliasesCtx(ctx context.Context, actions ...*AliasAction) error {
+	list := make([]map[string]interface{}, len(actions))
+	for i, a := range actions {
+		list[i] = a.Source()
+	}
+	b, err := json.Marshal(map[string]interface{}{"actions": list})
+	if err != nil {
+		return fmt.Errorf("encode aliases: %w", err)
+	}
+	r.logRequest(ctx, "update aliases", "", string(b))
+
+	req := esapi.IndicesUpdateAliasesRequest{Body: strings.NewReader(string(b))}
+	res, err := req.Do(ctx, r.Client())
+	if err != nil {
+		return fmt.Errorf("update aliases request: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return newESError("update aliases", res)
+	}
+	r.logResponse(ctx, "update aliases", res)
+	return nil
+}
+
+// AddAlias points alias at indexName. Use UpdateAliases with NewAddAlias for
+// a filter, routing or the write flag.
+func (r *SearchEngine) AddAlias(indexName, alias string) error {
+	return r.AddAliasCtx(context.Background(), indexName, alias)
+}
+
+// AddAliasCtx is like AddAlias but carries ctx to the request.
+func (r *SearchEngine) AddAliasCtx(ctx context.Context, indexName, alias string) error {
+	return r.UpdateAliasesCtx(ctx, NewAddAlias(indexName, alias))
+}
+
+// RemoveAlias removes alias from indexName.
+func (r *SearchEngine) RemoveAlias(indexName, alias string) error {
+	return r.RemoveAliasCtx(context.Background(), indexName, alias)
+}
+
+// RemoveAliasCtx is like RemoveAlias but carries ctx to the request.
+func (r *SearchEngine) RemoveAliasCtx(ctx context.Context, indexName, alias string) error {
+	return r.UpdateAliasesCtx(ctx, NewRemoveAlias(indexName, alias))
+}
+
+// SwapAlias moves alias from the index from to the index to atomically.
+func (r *SearchEngine) SwapAlias(alias, from, to string) error {
+	return r.SwapAliasCtx(context.Background(), alias, from, to)
+}
+
+// SwapAliasCtx is like SwapAlias but carries ctx to the request.
+func (r *SearchEngine) SwapAliasCtx(ctx context.Context, alias, from, to string) error {
+	return r.UpdateAliasesCtx(ctx, NewAddAlias(to, alias), NewRemoveAlias(from, alias))
+}
+
+// Aliases lists the aliases of the indices matching indexName, which may
+// contain wildcards, sorted by alias and index. An empty indexName lists
+// all.
+func (r *SearchEngine) Aliases(indexName string) ([]Alias, error) {
+	return r.AliasesCtx(context.Background(), indexName)
+}
+
+// AliasesCtx is like Aliases but carries ctx to the request.
+func (r *SearchEngine) AliasesCtx(ctx context.Context, indexName string) ([]Alias, error) {
+	req := esapi.IndicesGetAliasRequest{}
+	if indexName != "" {
+		req.Index = []string{indexName}
+	}
+	return r.getAliases(ctx, req)
+}
+
+// AliasIndices returns the indices alias points to, sorted, or none if there
+// is no such alias.
+func (r *SearchEngine) AliasIndices(alias string) ([]string, error) {
+	return r.AliasIndicesCtx(context.Background(), alias)
+}
+
+// AliasIndicesCtx is like AliasIndices but carries ctx to the request.
+func (r *SearchEngine) AliasIndicesCtx(ctx context.Context, alias string) ([]string, error) {
+	list, err := r.getAliases(ctx, esapi.IndicesGetAliasRequest{Name: []string{alias}})
+	if err != nil {
+		return nil, err
+	}
+	indices := make([]string, 0, len(list))
+	for _, a := range list {
+		indices = append(indices, a.Index)
+	}
+	sort.Strings(indices)
+	return indices, nil
+}
+
+func (r *SearchEngine) getAliases(ctx context.Context, req esapi.IndicesGetAliasRequest) ([]Alias, error) {
+	res, err := req.Do(ctx, r.Client())
+	if err != nil {
+		return nil, fmt.Errorf("get alias request: %w", err)
+	}
+	defer res.Body.Close()
+	// asked by name, a missing alias is a 404
+	if res.StatusCode == 404 && len(req.Name) > 0 {
+		return nil, nil
+	}
+	if res.IsError() {
+		return nil, newESError("get alias", res)
+	}
+	var body map[string]struct {
+		Aliases map[string]struct {
+			Filter        json.RawMessage `json:"filter"`
+			IndexRouting  string          `json:"index_routing"`
+			SearchRouting string          `json:"search_routing"`
+			IsWriteIndex  bool            `json:"is_write_index"`
+		} `json:"aliases"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("get alias decode: %w", err)
+	}
+	var list []Alias
+	for index, v := range body {
+		for name, a := range v.Aliases {
+			list = append(list, Alias{
+				Alias:         name,
+				Index:         index,
+				Filter:        a.Filter,
+				IndexRouting:  a.IndexRouting,
+				SearchRouting: a.SearchRouting,
+				IsWriteIndex:  a.IsWriteIndex,
+			})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Alias != list[j].Alias {
+			return list[i].Alias < list[j].Alias
+		}
+		return list[i].Index < list[j].Index
+	})
+	return list, nil
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestAliases(t *testing.T) {
+	var updated string
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/_aliases":
+			b, _ := io.ReadAll(r.Body)
+			updated = string(b)
+			w.Write([]byte(`{"acknowledged":true}`))
+		case r.URL.Path == "/recipe_data_v*/_alias":
+			w.Write([]byte(`{
+				"recipe_data_v2":{"aliases":{"recipe_data":{"is_write_index":true},
+					"user_1":{"filter":{"term":{"user_id":"1"}},"index_routing":"1","search_routing":"1"}}},
+				"recipe_data_v1":{"aliases":{"recipe_data":{}}},
+				"recipe_data_v0":{"aliases":{}}}`))
+		case r.URL.Path == "/_alias/missing":
+			w.WriteHeader(404)
+			w.Write([]byte(`{"error":"alias [missing] missing","status":404}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	err := s.UpdateAliases(
+		NewAddAlias("recipe_data_v2", "user_1").Filter(NewTermQuery("user_id", "1")).Routing("1"),
+		NewAddAlias("recipe_data_v2", "recipe_data").WriteIndex(true),
+		NewRemoveAlias("recipe_data_v1", "recipe_data"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"actions":[{"add":{"alias":"user_1","filter":{"term":{"user_id":"1"}},"index":"recipe_data_v2","routing":"1"}},` +
+		`{"add":{"alias":"recipe_data","index":"recipe_data_v2","is_write_index":true}},` +
+		`{"remove":{"alias":"recipe_data","index":"recipe_data_v1"}}]}`
+	if updated != want {
+		t.Errorf("update\n got %s\nwant %s", updated, want)
+	}
+
+	list, err := s.Aliases("recipe_data_v*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 || list[0].Index != "recipe_data_v1" || !list[1].IsWriteIndex ||
+		list[2].Alias != "user_1" || list[2].IndexRouting != "1" || string(list[2].Filter) != `{"term":{"user_id":"1"}}` {
+		t.Errorf("unexpected aliases %+v", list)
+	}
+
+	if indices, err := s.AliasIndices("missing"); err != nil || len(indices) != 0 {
+		t.Errorf("AliasIndices(missing) = %v, %v", indices, err)
+	}
+}
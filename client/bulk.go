@@ -0,0 +1,218 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// BulkItemResult is the outcome of one action of a bulk request.
+type BulkItemResult struct {
+	DocOpt
+	Action string      `json:"-"`
+	Status int         `json:"status"`
+	Error  *ErrorCause `json:"error,omitempty"`
+}
+
+// Failed reports whether the item was rejected.
+func (it BulkItemResult) Failed() bool {
+	return it.Error != nil || it.Status >= 300
+}
+
+// Err returns the item failure as an *ESError, or nil when it succeeded.
+func (it BulkItemResult) Err() error {
+	if !it.Failed() {
+		return nil
+	}
+	e := &ESError{Op: "bulk " + it.Action, StatusCode: it.Status, Index: it.Index}
+	if it.Error != nil {
+		e.Type, e.Reason = it.Error.Type, it.Error.Reason
+		e.RootCause = []ErrorCause{*it.Error}
+	}
+	return e
+}
+
+// BulkReport lists the outcome of every action of a bulk request, in request
+// order.
+type BulkReport struct {
+	Took  int
+	Items []BulkItemResult
+}
+
+// Failed returns the rejected items.
+func (b *BulkReport) Failed() []BulkItemResult {
+	var l []BulkItemResult
+	for _, it := range b.Items {
+		if it.Failed() {
+			l = append(l, it)
+		}
+	}
+	return l
+}
+
+// IDs returns the document id of every item, in request order.
+func (b *BulkReport) IDs() []string {
+	ids := make([]string, 0, len(b.Items))
+	for _, it := range b.Items {
+		ids = append(ids, it.ID)
+	}
+	return ids
+}
+
+// BulkError is returned when the cluster accepted a bulk request but rejected
+// some of its items. Report holds the outcome of every item; Err, when set,
+// is what stopped the retries of the rejected items, such as ctx's error.
+type BulkError struct {
+	Op     string
+	Report *BulkReport
+	Err    error
+}
+
+func (e *BulkError) Error() string {
+	failed := e.Report.Failed()
+	msg := fmt.Sprintf("%s: %d of %d items failed", e.Op, len(failed), len(e.Report.Items))
+	if len(failed) > 0 {
+		msg += fmt.Sprintf(" (first: id %q: %v)", failed[0].ID, failed[0].Err())
+	}
+	if e.Err != nil {
+		msg += ": retries stopped: " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the error that stopped the retries, if any.
+func (e *BulkError) Unwrap() error { return e.Err }
+
+// Is matches a sentinel error when any failed item matches it, so that
+// errors.Is(err, ErrVersionConflict) works on bulk results.
+func (e *BulkError) Is(target error) bool {
+	for _, it := range e.Report.Failed() {
+		if it.Err().(*ESError).Is(target) {
+			return true
+		}
+	}
+	return false
+}
+
+// bulkEntry is one action of a bulk request: the action metadata line and,
+// except for deletes, the source line.
+type bulkEntry struct {
+	action string
+	meta   string
+	body   string
+}
+
+func newBulkEntry(action, indexName, id, body string) bulkEntry {
+	return bulkEntry{action: action, meta: bulkMeta(action, indexName, id), body: body}
+}
+
+// writeTo writes the source line for every action but delete, even when
+// body is empty, so that a bad item cannot shift the lines of the next ones.
+func (e bulkEntry) writeTo(buf *strings.Builder) {
+	buf.WriteString(e.meta)
+	buf.WriteByte('\n')
+	if e.action != "delete" {
+		buf.WriteString(e.body)
+		buf.WriteByte('\n')
+	}
+}
+
+func bulkMeta(action, indexName, id string) string {
+	m := map[string]interface{}{"_index": indexName}
+	if id != "" {
+		m["_id"] = id
+	}
+	b, _ := json.Marshal(map[string]interface{}{action: m})
+	return string(b)
+}
+
+// executeBulk sends entries as one bulk request, resubmitting the items
+// rejected with a retryable status when the engine has a RetryPolicy. The
+// report is returned whenever a response could be decoded, also together
+// with a *BulkError.
+func (r *SearchEngine) executeBulk(ctx context.Context, op, indexName, refresh string, entries []bulkEntry) (*BulkReport, error) {
+	report, err := r.sendBulk(ctx, op, indexName, refresh, entries)
+	if err != nil {
+		return nil, err
+	}
+	var stopped error
+	if p := r.retry; p != nil {
+		for attempt := 1; attempt < p.MaxAttempts; attempt++ {
+			var pos []int
+			for i, it := range report.Items {
+				if i < len(entries) && it.Failed() && p.retryable(it.Status) {
+					pos = append(pos, i)
+				}
+			}
+			if len(pos) == 0 {
+				break
+			}
+			r.logf(ctx, LevelWarn, op+" retrying items", "index", indexName, "items", len(pos), "attempt", attempt+1)
+			if err := p.wait(ctx, attempt); err != nil {
+				stopped = err
+				break
+			}
+			retry := make([]bulkEntry, len(pos))
+			for j, i := range pos {
+				retry[j] = entries[i]
+			}
+			again, err := r.sendBulk(ctx, op, indexName, refresh, retry)
+			if err != nil {
+				r.logf(ctx, LevelWarn, op+" retry failed", "index", indexName, "error", err)
+				stopped = err
+				break
+			}
+			report.Took += again.Took
+			for j, i := range pos {
+				if j < len(again.Items) {
+					report.Items[i] = again.Items[j]
+				}
+			}
+		}
+	}
+	if failed := report.Failed(); len(failed) > 0 {
+		r.logf(ctx, LevelWarn, op+" items failed", "index", indexName, "failed", len(failed), "total", len(report.Items))
+		return report, &BulkError{Op: op, Report: report, Err: stopped}
+	}
+	return report, nil
+}
+
+// sendBulk sends entries once and decodes the per-item results.
+func (r *SearchEngine) sendBulk(ctx context.Context, op, indexName, refresh string, entries []bulkEntry) (*BulkReport, error) {
+	var buf strings.Builder
+	for _, e := range entries {
+		e.writeTo(&buf)
+	}
+	r.logRequest(ctx, op, indexName, buf.String())
+	req := esapi.BulkRequest{
+		Index:   indexName,
+		Body:    strings.NewReader(buf.String()),
+		Refresh: refresh,
+	}
+	res, err := req.Do(ctx, r.Client())
+	if err != nil {
+		return nil, fmt.Errorf("%s request: %w", op, err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, newESError(op, res)
+	}
+
+	var body BulkOpt
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("%s decode: %w", op, err)
+	}
+
+	report := &BulkReport{Took: body.Took}
+	for _, item := range body.Items {
+		for action, it := range item {
+			it.Action = action
+			report.Items = append(report.Items, it)
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,285 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BulkIndexerConfig controls how a BulkIndexer batches and sends items.
+// Zero values take the defaults noted on each field.
+type BulkIndexerConfig struct {
+	Index         string        // index for items that do not name one
+	NumWorkers    int           // concurrent flushing workers, default runtime.NumCPU()
+	FlushBytes    int           // flush a worker's batch at this payload size, default 5MB
+	FlushItems    int           // flush a worker's batch at this many items, default 1000
+	FlushInterval time.Duration // flush non-empty batches this often, default 30s
+	Refresh       string        // refresh parameter of each bulk request: "", "true" or "wait_for"
+
+	// OnError is called when a whole bulk request fails. The items of that
+	// request also get their OnFailure callback.
+	OnError func(ctx context.Context, err error)
+}
+
+// BulkIndexerItem is one action queued on a BulkIndexer.
+type BulkIndexerItem struct {
+	Action     string // "index", "create", "update" or "delete"
+	Index      string // defaults to BulkIndexerConfig.Index
+	DocumentID string
+	// Body is the source line of the action: the document for index and
+	// create, the update body ({"doc": ...}) for update, empty for delete.
+	Body string
+
+	OnSuccess func(ctx context.Context, item BulkIndexerItem, res BulkItemResult)
+	OnFailure func(ctx context.Context, item BulkIndexerItem, res BulkItemResult, err error)
+}
+
+// DocItem builds a BulkIndexerItem for doc. For "update" the document is
+// sent as a partial update.
+func DocItem(action string, doc SearchEngine_Doc) BulkIndexerItem {
+	it := BulkIndexerItem{Action: action, DocumentID: doc.GetID()}
+	switch action {
+	case "delete":
+	case "update":
+		it.Body = `{"doc":` + doc.ToJSON() + `}`
+	default:
+		it.Body = doc.ToJSON()
+	}
+	return it
+}
+
+// BulkIndexerStats counts what a BulkIndexer did so far.
+type BulkIndexerStats struct {
+	NumAdded    uint64
+	NumFlushed  uint64
+	NumFailed   uint64
+	NumIndexed  uint64
+	NumCreated  uint64
+	NumUpdated  uint64
+	NumDeleted  uint64
+	NumRequests uint64
+}
+
+// ErrBulkIndexerClosed is returned by Add once Close was called.
+var ErrBulkIndexerClosed = errors.New("bulk indexer closed")
+
+// BulkIndexer streams items into bulk requests from any number of
+// goroutines. Create it with SearchEngine.NewBulkIndexer and always Close it.
+type BulkIndexer struct {
+	engine *SearchEngine
+	cfg    BulkIndexerConfig
+	queue  chan BulkIndexerItem
+	wg     sync.WaitGroup
+
+	// ctx carries the flushes; Close cancels it when its own ctx ends.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	closed  bool
+	pending sync.WaitGroup // Add calls past the closed check
+	done    chan struct{}  // closed once the workers returned
+
+	stats struct {
+		added, flushed, failed, indexed, created, updated, deleted, requests uint64
+	}
+}
+
+// NewBulkIndexer starts the workers of a BulkIndexer.
+func (r *SearchEngine) NewBulkIndexer(cfg BulkIndexerConfig) (*BulkIndexer, error) {
+	if cfg.NumWorkers <= 0 {
+		cfg.NumWorkers = runtime.NumCPU()
+	}
+	if cfg.FlushBytes <= 0 {
+		cfg.FlushBytes = 5 << 20
+	}
+	if cfg.FlushItems <= 0 {
+		cfg.FlushItems = 1000
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 30 * time.Second
+	}
+	b := &BulkIndexer{
+		engine: r,
+		cfg:    cfg,
+		queue:  make(chan BulkIndexerItem, cfg.NumWorkers),
+		done:   make(chan struct{}),
+	}
+	b.ctx, b.cancel = context.WithCancel(context.Background())
+	for i := 0; i < cfg.NumWorkers; i++ {
+		b.wg.Add(1)
+		go b.work()
+	}
+	return b, nil
+}
+
+// Add queues item, blocking while all workers are busy. It is safe for
+// concurrent use.
+func (b *BulkIndexer) Add(ctx context.Context, item BulkIndexerItem) error {
+	switch item.Action {
+	case "index", "create", "update", "delete":
+	default:
+		return fmt.Errorf("unknown bulk action %q", item.Action)
+	}
+	if item.Index == "" {
+		item.Index = b.cfg.Index
+	}
+	if item.Index == "" {
+		return fmt.Errorf("Empty index")
+	}
+	if item.Action != "delete" && item.Body == "" {
+		return fmt.Errorf("Empty body")
+	}
+	if (item.Action == "update" || item.Action == "delete") && item.DocumentID == "" {
+		return fmt.Errorf("Empty id")
+	}
+
+	// the lock is not held across the send: a callback calling Add must
+	// not wait on a Close that waits on the callback's worker
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrBulkIndexerClosed
+	}
+	b.pending.Add(1)
+	b.mu.Unlock()
+	defer b.pending.Done()
+
+	select {
+	case b.queue <- item:
+		atomic.AddUint64(&b.stats.added, 1)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close flushes the queued items, stops the workers and returns the final
+// stats. If ctx ends first, Close cancels the bulk requests in flight and
+// returns ctx's error; the items not sent yet fail with context.Canceled.
+func (b *BulkIndexer) Close(ctx context.Context) (BulkIndexerStats, error) {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		go func() {
+			// Adds blocked on the queue finish once the workers drain it
+			b.pending.Wait()
+			close(b.queue)
+			b.wg.Wait()
+			b.cancel()
+			close(b.done)
+		}()
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-b.done:
+		return b.Stats(), nil
+	case <-ctx.Done():
+		b.cancel()
+		return b.Stats(), ctx.Err()
+	}
+}
+
+// Stats returns a snapshot of the counters.
+func (b *BulkIndexer) Stats() BulkIndexerStats {
+	return BulkIndexerStats{
+		NumAdded:    atomic.LoadUint64(&b.stats.added),
+		NumFlushed:  atomic.LoadUint64(&b.stats.flushed),
+		NumFailed:   atomic.LoadUint64(&b.stats.failed),
+		NumIndexed:  atomic.LoadUint64(&b.stats.indexed),
+		NumCreated:  atomic.LoadUint64(&b.stats.created),
+		NumUpdated:  atomic.LoadUint64(&b.stats.updated),
+		NumDeleted:  atomic.LoadUint64(&b.stats.deleted),
+		NumRequests: atomic.LoadUint64(&b.stats.requests),
+	}
+}
+
+func (b *BulkIndexer) work() {
+	defer b.wg.Done()
+	ticker := time.NewTicker(b.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	var (
+		items   []BulkIndexerItem
+		entries []bulkEntry
+		size    int
+	)
+	flush := func() {
+		if len(items) == 0 {
+			return
+		}
+		b.flush(b.ctx, items, entries)
+		items, entries, size = nil, nil, 0
+	}
+
+	for {
+		select {
+		case it, ok := <-b.queue:
+			if !ok {
+				flush()
+				return
+			}
+			e := newBulkEntry(it.Action, it.Index, it.DocumentID, it.Body)
+			items = append(items, it)
+			entries = append(entries, e)
+			size += len(e.meta) + len(e.body) + 2
+			if size >= b.cfg.FlushBytes || len(items) >= b.cfg.FlushItems {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (b *BulkIndexer) flush(ctx context.Context, items []BulkIndexerItem, entries []bulkEntry) {
+	atomic.AddUint64(&b.stats.requests, 1)
+	report, err := b.engine.executeBulk(ctx, "bulk indexer", "", b.cfg.Refresh, entries)
+	if report == nil {
+		if b.cfg.OnError != nil {
+			b.cfg.OnError(ctx, err)
+		}
+		atomic.AddUint64(&b.stats.failed, uint64(len(items)))
+		for _, it := range items {
+			if it.OnFailure != nil {
+				it.OnFailure(ctx, it, BulkItemResult{Action: it.Action}, err)
+			}
+		}
+		return
+	}
+
+	for i, it := range items {
+		var res BulkItemResult
+		if i < len(report.Items) {
+			res = report.Items[i]
+		} else {
+			res = BulkItemResult{Action: it.Action, Status: 500, Error: &ErrorCause{Type: "missing_item", Reason: "no result for item"}}
+		}
+		if res.Failed() {
+			atomic.AddUint64(&b.stats.failed, 1)
+			if it.OnFailure != nil {
+				it.OnFailure(ctx, it, res, res.Err())
+			}
+			continue
+		}
+		atomic.AddUint64(&b.stats.flushed, 1)
+		switch res.Action {
+		case "index":
+			atomic.AddUint64(&b.stats.indexed, 1)
+		case "create":
+			atomic.AddUint64(&b.stats.created, 1)
+		case "update":
+			atomic.AddUint64(&b.stats.updated, 1)
+		case "delete":
+			atomic.AddUint64(&b.stats.deleted, 1)
+		}
+		if it.OnSuccess != nil {
+			it.OnSuccess(ctx, it, res)
+		}
+	}
+}
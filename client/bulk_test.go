@@ -0,0 +1,336 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestEngine(t *testing.T, h http.HandlerFunc, opts ...Option) *SearchEngine {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		h(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	s, err := NewSearchEngine(append([]Option{WithAddresses(srv.URL)}, opts...)...)
+	if err != nil {
+		t.Fatalf("NewSearchEngine: %v", err)
+	}
+	return s
+}
+
+func TestBulkDeleteReportsFailedItems(t *testing.T) {
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"took":3,"errors":true,"items":[
+			{"delete":{"_index":"recipe_data","_id":"1","result":"deleted","status":200}},
+			{"delete":{"_index":"recipe_data","_id":"2","status":409,"error":{"type":"version_conflict_engine_exception","reason":"[2]: version conflict"}}}
+		]}`))
+	})
+
+	err := s.BulkDelete("recipe_data", []string{"1", "2"})
+	var be *BulkError
+	if !errors.As(err, &be) {
+		t.Fatalf("expected *BulkError, got %v", err)
+	}
+	failed := be.Report.Failed()
+	if len(be.Report.Items) != 2 || len(failed) != 1 || failed[0].ID != "2" {
+		t.Fatalf("unexpected report: %+v", be.Report)
+	}
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("expected ErrVersionConflict, got %v", err)
+	}
+}
+
+func TestBulkCreateRequestError(t *testing.T) {
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"type":"index_not_found_exception","reason":"no such index [recipe_data]","index":"recipe_data"},"status":404}`))
+	})
+
+	_, err := s.BulkCreate("recipe_data", []SearchEngine_Doc{testDoc{ID: "1"}})
+	var ee *ESError
+	if !errors.As(err, &ee) || ee.Index != "recipe_data" {
+		t.Fatalf("expected *ESError for recipe_data, got %v", err)
+	}
+	if !errors.Is(err, ErrIndexNotFound) {
+		t.Errorf("expected ErrIndexNotFound, got %v", err)
+	}
+}
+
+type testDoc struct {
+	ID    string `json:"-"`
+	Title string `json:"title"`
+}
+
+func (d testDoc) ToJSON() string { return `{"title":"` + d.Title + `"}` }
+func (d testDoc) GetID() string  { return d.ID }
+func (d testDoc) SetID(string)   {}
+func (d testDoc) FieldsToMap() map[string]interface{} {
+	return map[string]interface{}{"title": d.Title}
+}
+
+func TestBulkIndexerFlushesByItemCount(t *testing.T) {
+	var requests int32
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		var items []string
+		sc := bufio.NewScanner(r.Body)
+		for sc.Scan() {
+			if strings.HasPrefix(sc.Text(), `{"index"`) {
+				items = append(items, `{"index":{"_index":"recipe_data","_id":"x","result":"created","status":201}}`)
+			}
+		}
+		fmt.Fprintf(w, `{"took":1,"errors":false,"items":[%s]}`, strings.Join(items, ","))
+	})
+
+	bi, err := s.NewBulkIndexer(BulkIndexerConfig{Index: "recipe_data", NumWorkers: 1, FlushItems: 10, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ok int32
+	var wg sync.WaitGroup
+	for g := 0; g < 5; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				err := bi.Add(context.Background(), BulkIndexerItem{
+					Action: "index",
+					Body:   `{"title":"apple pie"}`,
+					OnSuccess: func(context.Context, BulkIndexerItem, BulkItemResult) {
+						atomic.AddInt32(&ok, 1)
+					},
+				})
+				if err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats, err := bi.Close(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.NumAdded != 25 || stats.NumIndexed != 25 || stats.NumFailed != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if ok != 25 {
+		t.Errorf("OnSuccess called %d times", ok)
+	}
+	if requests != 3 {
+		t.Errorf("expected 3 bulk requests, got %d", requests)
+	}
+	if err := bi.Add(context.Background(), BulkIndexerItem{Action: "index", Body: "{}"}); err != ErrBulkIndexerClosed {
+		t.Errorf("Add after Close: %v", err)
+	}
+}
+
+func TestBulkIndexerAddFromCallbackDuringClose(t *testing.T) {
+	release := make(chan struct{})
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		var items []string
+		sc := bufio.NewScanner(r.Body)
+		for sc.Scan() {
+			if strings.HasPrefix(sc.Text(), `{"index"`) {
+				items = append(items, `{"index":{"_index":"recipe_data","_id":"x","result":"created","status":201}}`)
+			}
+		}
+		fmt.Fprintf(w, `{"took":1,"errors":false,"items":[%s]}`, strings.Join(items, ","))
+	})
+
+	bi, err := s.NewBulkIndexer(BulkIndexerConfig{Index: "recipe_data", NumWorkers: 1, FlushItems: 1, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requeued := make(chan error, 1)
+	item := BulkIndexerItem{Action: "index", Body: `{"title":"apple pie"}`}
+	first := item
+	first.OnSuccess = func(ctx context.Context, it BulkIndexerItem, _ BulkItemResult) {
+		requeued <- bi.Add(ctx, item)
+	}
+	if err := bi.Add(context.Background(), first); err != nil {
+		t.Fatal(err)
+	}
+	// the worker is stuck on the first flush: fill the queue and block one more Add
+	if err := bi.Add(context.Background(), item); err != nil {
+		t.Fatal(err)
+	}
+	go bi.Add(context.Background(), item)
+	time.Sleep(20 * time.Millisecond)
+
+	closed := make(chan error, 1)
+	go func() {
+		_, err := bi.Close(context.Background())
+		closed <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close deadlocked")
+	}
+	if err := <-requeued; err != ErrBulkIndexerClosed {
+		t.Errorf("Add from a callback during Close: %v", err)
+	}
+	if stats := bi.Stats(); stats.NumAdded != 3 || stats.NumIndexed != 3 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestBulkIndexerCloseCancelsFlush(t *testing.T) {
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body) // the server notices the client leaving only past the body
+		<-r.Context().Done()
+	})
+
+	bi, err := s.NewBulkIndexer(BulkIndexerConfig{Index: "recipe_data", NumWorkers: 1, FlushItems: 1, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := make(chan error, 1)
+	err = bi.Add(context.Background(), BulkIndexerItem{
+		Action: "index",
+		Body:   `{"title":"apple pie"}`,
+		OnFailure: func(_ context.Context, _ BulkIndexerItem, _ BulkItemResult, err error) {
+			failed <- err
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := bi.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Close: %v", err)
+	}
+	select {
+	case err := <-failed:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the flush was not cancelled")
+	}
+}
+
+// blankDoc fails to encode the way CardRender does: ToJSON returns "".
+type blankDoc struct{ testDoc }
+
+func (blankDoc) ToJSON() string { return "" }
+
+func TestBulkRejectsEmptyBodies(t *testing.T) {
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	})
+	docs := []SearchEngine_Doc{blankDoc{testDoc{ID: "1"}}, testDoc{ID: "2", Title: "b"}}
+
+	if _, err := s.BulkCreate("recipe_data", docs); err == nil {
+		t.Error("BulkCreate sent an empty body")
+	}
+	if err := NewRepository[blankDoc](s, "recipe_data").SaveAll([]*blankDoc{{}}); err == nil {
+		t.Error("SaveAll sent an empty body")
+	}
+}
+
+func TestBulkEntryKeepsLinesAligned(t *testing.T) {
+	var buf strings.Builder
+	for _, e := range []bulkEntry{
+		newBulkEntry("index", "recipe_data", "", ""),
+		newBulkEntry("delete", "recipe_data", "1", ""),
+		newBulkEntry("index", "recipe_data", "", `{"title":"b"}`),
+	} {
+		e.writeTo(&buf)
+	}
+	want := `{"index":{"_index":"recipe_data"}}` + "\n\n" +
+		`{"delete":{"_id":"1","_index":"recipe_data"}}` + "\n" +
+		`{"index":{"_index":"recipe_data"}}` + "\n" + `{"title":"b"}` + "\n"
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+// bulkSizes answers bulk index requests and sends the item count of each on
+// the returned channel.
+func bulkSizes(t *testing.T) (*SearchEngine, <-chan int) {
+	sizes := make(chan int, 10)
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		var items []string
+		sc := bufio.NewScanner(r.Body)
+		for sc.Scan() {
+			if strings.HasPrefix(sc.Text(), `{"index"`) {
+				items = append(items, `{"index":{"_index":"recipe_data","_id":"x","result":"created","status":201}}`)
+			}
+		}
+		fmt.Fprintf(w, `{"took":1,"errors":false,"items":[%s]}`, strings.Join(items, ","))
+		sizes <- len(items)
+	})
+	return s, sizes
+}
+
+func TestBulkIndexerFlushesBySize(t *testing.T) {
+	s, sizes := bulkSizes(t)
+	// an item is 57 bytes with its action line, so the second one flushes
+	bi, err := s.NewBulkIndexer(BulkIndexerConfig{Index: "recipe_data", NumWorkers: 1, FlushBytes: 100, FlushItems: 1000, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bi.Close(context.Background())
+	for i := 0; i < 2; i++ {
+		if err := bi.Add(context.Background(), BulkIndexerItem{Action: "index", Body: `{"title":"apple pie"}`}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case n := <-sizes:
+		if n != 2 {
+			t.Errorf("expected a flush of 2 items, got %d", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no flush before Close")
+	}
+}
+
+func TestBulkIndexerFlushesByInterval(t *testing.T) {
+	s, sizes := bulkSizes(t)
+	bi, err := s.NewBulkIndexer(BulkIndexerConfig{Index: "recipe_data", NumWorkers: 1, FlushItems: 1000, FlushInterval: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bi.Close(context.Background())
+	if err := bi.Add(context.Background(), BulkIndexerItem{Action: "index", Body: `{"title":"apple pie"}`}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case n := <-sizes:
+		if n != 1 {
+			t.Errorf("expected a flush of 1 item, got %d", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no flush before Close")
+	}
+	if stats := bi.Stats(); stats.NumRequests != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
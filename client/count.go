@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// Count returns the number of documents of indexName matching q, without
+// fetching any of them. A nil q counts all documents.
+func (r *SearchEngine) Count(indexName string, q Query) (int64, error) {
+	return r.CountCtx(context.Background(), indexName, q)
+}
+
+// CountCtx is like Count but carries ctx to the request.
+func (r *SearchEngine) CountCtx(ctx context.Context, indexName string, q Query) (int64, error) {
+	body, err := searchBody(q)
+	if err != nil {
+		return 0, err
+	}
+	r.logRequest(ctx, "count", indexName, body)
+
+	req := esapi.CountRequest{
+		Index: []string{indexName},
+		Body:  strings.NewReader(body),
+	}
+	res, err := req.Do(ctx, r.Client())
+	if err != nil {
+		return 0, fmt.Errorf("count request: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return 0, newESError("count", res)
+	}
+	r.logResponse(ctx, "count", res)
+
+	var cnt struct {
+		Count int64 `json:"count"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&cnt); err != nil {
+		return 0, fmt.Errorf("count decode: %w", err)
+	}
+	return cnt.Count, nil
+}
+
+// DocExists reports whether indexName holds a document with the given id.
+// A missing index is reported as an error.
+func (r *SearchEngine) DocExists(indexName string, id string) (bool, error) {
+	return r.DocExistsCtx(context.Background(), indexName, id)
+}
+
+// DocExistsCtx is like DocExists but carries ctx to the request.
+func (r *SearchEngine) DocExistsCtx(ctx context.Context, indexName string, id string) (bool, error) {
+	req := esapi.ExistsRequest{
+		Index:      indexName,
+		DocumentID: id,
+	}
+	res, err := req.Do(ctx, r.Client())
+	if err != nil {
+		return false, fmt.Errorf("doc exists request: %w", err)
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case 200:
+		return true, nil
+	case 404:
+		// a HEAD response has no body telling a missing document from a
+		// missing index, so ask the index
+		ok, err := r.IndexExistsCtx(ctx, indexName)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, &ESError{Op: "doc exists", StatusCode: 404, Type: "index_not_found_exception", Index: indexName, Reason: "no such index [" + indexName + "]"}
+		}
+		return false, nil
+	}
+	return false, newESError("doc exists", res)
+}
+
+// IndexExists reports whether indexName exists, as an index or an alias.
+func (r *SearchEngine) IndexExists(indexName string) (bool, error) {
+	return r.IndexExistsCtx(context.Background(), indexName)
+}
+
+// IndexExistsCtx is like IndexExists but carries ctx to the request.
+func (r *SearchEngine) IndexExistsCtx(ctx context.Context, indexName string) (bool, error) {
+	req := esapi.IndicesExistsRequest{Index: []string{indexName}}
+	res, err := req.Do(ctx, r.Client())
+	if err != nil {
+		return false, fmt.Errorf("index exists request: %w", err)
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case 200:
+		return true, nil
+	case 404:
+		return false, nil
+	}
+	return false, newESError("index exists", res)
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestCountAndExists(t *testing.T) {
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/recipe_data/_count":
+			b, _ := io.ReadAll(r.Body)
+			if string(b) != `{"query":{"term":{"meal":"dinner"}}}` {
+				t.Errorf("count body %s", b)
+			}
+			w.Write([]byte(`{"count":42,"_shards":{"total":1,"successful":1,"failed":0}}`))
+		case r.Method == http.MethodHead && r.URL.Path == "/recipe_data/_doc/1":
+			w.WriteHeader(200)
+		case r.Method == http.MethodHead && r.URL.Path == "/recipe_data/_doc/2":
+			w.WriteHeader(404)
+		case r.Method == http.MethodHead && r.URL.Path == "/missing/_doc/1":
+			w.WriteHeader(404)
+		case r.Method == http.MethodHead && r.URL.Path == "/recipe_data":
+			w.WriteHeader(200)
+		case r.Method == http.MethodHead && r.URL.Path == "/missing":
+			w.WriteHeader(404)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	if n, err := s.Count("recipe_data", NewTermQuery("meal", "dinner")); err != nil || n != 42 {
+		t.Errorf("Count = %d, %v", n, err)
+	}
+	if ok, err := s.DocExists("recipe_data", "1"); err != nil || !ok {
+		t.Errorf("DocExists(1) = %v, %v", ok, err)
+	}
+	if ok, err := s.DocExists("recipe_data", "2"); err != nil || ok {
+		t.Errorf("DocExists(2) = %v, %v", ok, err)
+	}
+	if _, err := s.DocExists("missing", "1"); !errors.Is(err, ErrIndexNotFound) {
+		t.Errorf("DocExists on a missing index: %v", err)
+	}
+	if ok, err := s.IndexExists("recipe_data"); err != nil || !ok {
+		t.Errorf("IndexExists(recipe_data) = %v, %v", ok, err)
+	}
+	if ok, err := s.IndexExists("missing"); err != nil || ok {
+		t.Errorf("IndexExists(missing) = %v, %v", ok, err)
+	}
+}
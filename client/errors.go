@@ -0,0 +1,176 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// Sentinel errors matched by ESError through errors.Is.
+var (
+	// ErrNotFound matches a 404 for a missing document or resource. A
+	// missing index matches ErrIndexNotFound instead.
+	ErrNotFound        = errors.New("not found")
+	ErrIndexNotFound   = errors.New("index not found")
+	ErrVersionConflict = errors.New("version conflict")
+	ErrMapping         = errors.New("mapping error")
+	ErrTooManyRequests = errors.New("too many requests")
+)
+
+// ErrFieldNotAllowed is returned, without a request being sent, for field
+// names outside the list given to WithAllowedFields.
+var ErrFieldNotAllowed = errors.New("field not allowed")
+
+// ErrNoIndexName is returned, without a request being sent, by the index
+// operations given an empty name, which the cluster would apply to all
+// indices.
+var ErrNoIndexName = errors.New("no index name")
+
+// ErrNoSettings is returned, without a request being sent, by
+// UpdateSettings given no dynamic setting to change.
+var ErrNoSettings = errors.New("no settings to update")
+
+// ErrBreakingMapping matches a *MappingConflictError.
+var ErrBreakingMapping = errors.New("breaking mapping change")
+
+// MappingConflictError reports mapping changes an existing index cannot
+// take. They need a new index and a reindex.
+type MappingConflictError struct {
+	Index   string
+	Changes []MappingChange
+}
+
+func (e *MappingConflictError) Error() string {
+	list := make([]string, len(e.Changes))
+	for i, c := range e.Changes {
+		list[i] = c.String()
+	}
+	return fmt.Sprintf("index %s: %d breaking mapping changes: %s", e.Index, len(list), strings.Join(list, "; "))
+}
+
+func (e *MappingConflictError) Is(target error) bool {
+	return target == ErrBreakingMapping
+}
+
+// ShardFailureError reports an index operation that failed on some shards.
+type ShardFailureError struct {
+	Op     string
+	Result ShardsResult
+}
+
+func (e *ShardFailureError) Error() string {
+	msg := fmt.Sprintf("%s: %d of %d shards failed", e.Op, e.Result.Failed, e.Result.Total)
+	if len(e.Result.Failures) > 0 {
+		f := e.Result.Failures[0]
+		msg += fmt.Sprintf(": [%s][%d] %s: %s", f.Index, f.Shard, f.Reason.Type, f.Reason.Reason)
+	}
+	return msg
+}
+
+// ESError is an error response returned by Elasticsearch.
+type ESError struct {
+	Op         string       // operation that failed, such as "get one"
+	StatusCode int          // HTTP status code
+	Type       string       // error type, such as "index_not_found_exception"
+	Reason     string       // human readable reason
+	Index      string       // index the error refers to, if any
+	RootCause  []ErrorCause // underlying causes reported by the cluster
+}
+
+// ErrorCause is one entry of an error's root_cause list.
+type ErrorCause struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+	Index  string `json:"index,omitempty"`
+}
+
+func (e *ESError) Error() string {
+	msg := fmt.Sprintf("%s: %d %s", e.Op, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Type != "" {
+		msg += ": " + e.Type
+	}
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+// Is reports whether e matches one of the sentinel errors of this package.
+func (e *ESError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound && e.Type != "index_not_found_exception"
+	case ErrIndexNotFound:
+		return e.Type == "index_not_found_exception"
+	case ErrVersionConflict:
+		return e.StatusCode == http.StatusConflict || e.Type == "version_conflict_engine_exception"
+	case ErrMapping:
+		return isMappingType(e.Type)
+	case ErrTooManyRequests:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+func isMappingType(t string) bool {
+	switch t {
+	case "mapper_parsing_exception", "document_parsing_exception",
+		"strict_dynamic_mapping_exception":
+		return true
+	}
+	return false
+}
+
+type errorBody struct {
+	Error  json.RawMessage `json:"error"`
+	Status int             `json:"status"`
+	Index  string          `json:"_index"`
+	Found  *bool           `json:"found"`
+	Result string          `json:"result"`
+}
+
+type errorDetail struct {
+	Type      string       `json:"type"`
+	Reason    string       `json:"reason"`
+	Index     string       `json:"index"`
+	RootCause []ErrorCause `json:"root_cause"`
+}
+
+// newESError builds an *ESError from an error response and consumes its body.
+func newESError(op string, res *esapi.Response) error {
+	e := &ESError{Op: op, StatusCode: res.StatusCode}
+	if res.Body == nil {
+		return e
+	}
+	b, err := io.ReadAll(res.Body)
+	if err != nil || len(b) == 0 {
+		return e
+	}
+	var body errorBody
+	if err := json.Unmarshal(b, &body); err != nil {
+		e.Reason = string(b)
+		return e
+	}
+	e.Index = body.Index
+	if len(body.Error) > 0 {
+		var d errorDetail
+		if err := json.Unmarshal(body.Error, &d); err == nil {
+			e.Type, e.Reason, e.RootCause = d.Type, d.Reason, d.RootCause
+			if d.Index != "" {
+				e.Index = d.Index
+			}
+		} else {
+			// some endpoints report the error as a plain string
+			json.Unmarshal(body.Error, &e.Reason)
+		}
+	} else if body.Found != nil && !*body.Found || body.Result == "not_found" {
+		e.Type = "document_missing"
+		e.Reason = "document not found"
+	}
+	return e
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+func errorResponse(status int, body string) *esapi.Response {
+	res := &esapi.Response{StatusCode: status, Header: http.Header{}}
+	if body != "" {
+		res.Body = io.NopCloser(strings.NewReader(body))
+	}
+	return res
+}
+
+func TestNewESError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   ESError
+	}{
+		{"object", 404,
+			`{"error":{"type":"index_not_found_exception","reason":"no such index [x]","index":"x",
+			"root_cause":[{"type":"index_not_found_exception","reason":"no such index [x]","index":"x"}]},"status":404}`,
+			ESError{StatusCode: 404, Type: "index_not_found_exception", Reason: "no such index [x]", Index: "x",
+				RootCause: []ErrorCause{{Type: "index_not_found_exception", Reason: "no such index [x]", Index: "x"}}}},
+		{"string", 404, `{"error":"alias [a] missing","status":404}`,
+			ESError{StatusCode: 404, Reason: "alias [a] missing"}},
+		{"missing doc", 404, `{"_index":"recipe_data","_id":"1","found":false}`,
+			ESError{StatusCode: 404, Type: "document_missing", Reason: "document not found", Index: "recipe_data"}},
+		{"deleted doc", 404, `{"_index":"recipe_data","_id":"1","result":"not_found"}`,
+			ESError{StatusCode: 404, Type: "document_missing", Reason: "document not found", Index: "recipe_data"}},
+		{"not json", 502, `Bad Gateway`,
+			ESError{StatusCode: 502, Reason: "Bad Gateway"}},
+		{"no body", 503, ``,
+			ESError{StatusCode: 503}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newESError("get one", errorResponse(tt.status, tt.body))
+			e, ok := err.(*ESError)
+			if !ok {
+				t.Fatalf("got %T", err)
+			}
+			tt.want.Op = "get one"
+			if e.Op != tt.want.Op || e.StatusCode != tt.want.StatusCode || e.Type != tt.want.Type ||
+				e.Reason != tt.want.Reason || e.Index != tt.want.Index || len(e.RootCause) != len(tt.want.RootCause) {
+				t.Errorf("got %+v, want %+v", e, tt.want)
+			}
+			for i := range e.RootCause {
+				if e.RootCause[i] != tt.want.RootCause[i] {
+					t.Errorf("root cause %d: got %+v", i, e.RootCause[i])
+				}
+			}
+		})
+	}
+}
+
+func TestESErrorIs(t *testing.T) {
+	sentinels := []error{ErrNotFound, ErrIndexNotFound, ErrVersionConflict, ErrMapping, ErrTooManyRequests}
+	tests := []struct {
+		status int
+		typ    string
+		want   error // the only sentinel matched, or nil
+	}{
+		{404, "document_missing", ErrNotFound},
+		{404, "", ErrNotFound},
+		{404, "index_not_found_exception", ErrIndexNotFound},
+		{409, "version_conflict_engine_exception", ErrVersionConflict},
+		{409, "", ErrVersionConflict},
+		{400, "mapper_parsing_exception", ErrMapping},
+		{400, "document_parsing_exception", ErrMapping},
+		{400, "strict_dynamic_mapping_exception", ErrMapping},
+		{400, "illegal_argument_exception", nil},
+		{400, "parsing_exception", nil},
+		{429, "es_rejected_execution_exception", ErrTooManyRequests},
+		{500, "exception", nil},
+	}
+	for _, tt := range tests {
+		err := error(&ESError{Op: "op", StatusCode: tt.status, Type: tt.typ})
+		for _, s := range sentinels {
+			if got := errors.Is(err, s); got != (s == tt.want) {
+				t.Errorf("%d %s: errors.Is(%v) = %v", tt.status, tt.typ, s, got)
+			}
+		}
+	}
+}
@@ -5,17 +5,25 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"reflect"
+	"sort"
 	"strings"
 
-	"github.com/go-kitchen/esearch-client-go/util"
-
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-elasticsearch/v8/esapi"
 )
 
-type SearchEngine struct{}
+// SearchEngine talks to one Elasticsearch cluster. Build one per cluster with
+// NewSearchEngine; the zero value falls back to the package level ESClient
+// set up by InitESClient.
+type SearchEngine struct {
+	client *elasticsearch.Client
+	log    Logger
+	redact Redactor
+	retry  *RetryPolicy
+	fields []string // allowed field names, see WithAllowedFields
+}
+
 type SearchEngine_Doc interface {
 	ToJSON() string
 	GetID() string
@@ -23,50 +31,116 @@ type SearchEngine_Doc interface {
 	FieldsToMap() map[string]interface{}
 }
 
+// ESClient is the client used by a zero value SearchEngine.
+//
+// Deprecated: create instances with NewSearchEngine instead.
 var ESClient *elasticsearch.Client
 
-func InitESClient(addrArray []string) error {
+// InitESClient initializes the package level ESClient. opts take the same
+// authentication and TLS options as NewSearchEngine.
+//
+// Deprecated: use NewSearchEngine, which does not share state between
+// clusters.
+func InitESClient(addrArray []string, opts ...Option) error {
 	if addrArray == nil {
 		return fmt.Errorf("Empty Address list")
 	}
-	cfg := elasticsearch.Config{
-		Addresses: addrArray,
-	}
-	var err error
-	ESClient, err = elasticsearch.NewClient(cfg)
-	fmt.Println("ES initialized...")
-
+	s, err := NewSearchEngine(append([]Option{WithAddresses(addrArray...)}, opts...)...)
 	if err != nil {
-		fmt.Println("ES initialized error:", err)
 		return err
 	}
+	ESClient = s.client
 	return nil
 }
 
+// NewSearchEngine creates a SearchEngine that owns its own client.
+func NewSearchEngine(opts ...Option) (*SearchEngine, error) {
+	o := engineOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.err != nil {
+		return nil, o.err
+	}
+	s := &SearchEngine{client: o.client, log: o.log, redact: o.redact, retry: o.retry, fields: o.fields}
+	if s.client != nil {
+		return s, nil
+	}
+	if len(o.config.Addresses) == 0 && o.config.CloudID == "" {
+		return nil, fmt.Errorf("Empty Address list")
+	}
+	cfg, err := o.esConfig()
+	if err != nil {
+		return nil, err
+	}
+	s.client, err = elasticsearch.NewClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("new client: %w", err)
+	}
+	return s, nil
+}
+
+// Client returns the underlying Elasticsearch client.
+func (r *SearchEngine) Client() *elasticsearch.Client {
+	if r != nil && r.client != nil {
+		return r.client
+	}
+	return ESClient
+}
+
 type document struct {
 	Source interface{} `json:"_source"`
 }
 type queryResponse struct {
-	Took    int         `json:"took"`
-	Timeout bool        `json:"timeout"`
-	Hits    *hitSummary `json:"hits"`
+	PitID        string       `json:"pit_id"`
+	ScrollID     string       `json:"_scroll_id"`
+	Took         int          `json:"took"`
+	TimedOut     bool         `json:"timed_out"`
+	Shards       Shard        `json:"_shards"`
+	Hits         *hitSummary  `json:"hits"`
+	Aggregations Aggregations `json:"aggregations"`
 }
 type hitSummary struct {
-	Total total `json:"Total"`
-	Hits  []Hit `json:"hits"`
+	Total    TotalHits `json:"total"`
+	MaxScore float32   `json:"max_score"`
+	Hits     []Hit     `json:"hits"`
 }
 
 type Hit struct {
 	Index   string          `json:"_index"`
-	HitType string          `json::"_type"`
+	HitType string          `json:"_type"`
 	ID      string          `json:"_id"`
 	Score   float32         `json:"_score"`
 	Source  json.RawMessage `json:"_source"`
+	Sort    []interface{}   `json:"sort,omitempty"` // sort values, when the search was sorted; numbers are json.Number
+
+	Highlight map[string][]string      `json:"highlight,omitempty"` // snippets by field, see WithHighlight
+	Fields    map[string][]interface{} `json:"fields,omitempty"`    // values by field, see WithFields
+}
+
+// UnmarshalJSON decodes the numbers of Sort as json.Number, so that long
+// sort values survive the round trip into search_after.
+func (h *Hit) UnmarshalJSON(b []byte) error {
+	type plain Hit
+	aux := struct {
+		*plain
+		Sort json.RawMessage `json:"sort"`
+	}{plain: (*plain)(h)}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	h.Sort = nil
+	if len(aux.Sort) == 0 || string(aux.Sort) == "null" {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(aux.Sort))
+	dec.UseNumber()
+	return dec.Decode(&h.Sort)
 }
 
 type DocOpt struct {
 	Index       string `json:"_index"`
-	HitType     string `json::"_type"`
+	HitType     string `json:"_type"`
 	ID          string `json:"_id"`
 	Version     int    `json:"_version"`
 	Result      string `json:"result"`
@@ -76,56 +150,44 @@ type DocOpt struct {
 }
 
 type BulkOpt struct {
-	Took   int     `json:"took"`
-	Errors bool    `json:"errors"`
-	Items  []index `json:"items"`
-}
-
-type index struct {
-	Index DocOpt `json:"index"`
+	Took   int                         `json:"took"`
+	Errors bool                        `json:"errors"`
+	Items  []map[string]BulkItemResult `json:"items"`
 }
 
 type Shard struct {
 	Total      int `json:"total"`
-	Successful int `json:"total"`
+	Successful int `json:"successful"`
+	Skipped    int `json:"skipped,omitempty"`
 	Failed     int `json:"failed"`
 }
 
-type total struct {
-	Value    int    `json:"value"`
-	Relation string `json:"relation"`
+// Index creates indexName, with the settings and mapping of opts, unless it
+// exists already. Use IndexExists to only check.
+func (r *SearchEngine) Index(indexName string, opts ...IndexOption) error {
+	return r.IndexCtx(context.Background(), indexName, opts...)
 }
 
-type query_multiMatch struct {
-	Query                           string   `json:"query"`
-	Fields                          []string `json:"fields"`
-	AutoGenerateSynonymsPhraseQuery bool     `json:"auto_generate_synonyms_phrase_query"`
-	FuzzyTranspositions             bool     `json:"fuzzy_transpositions"`
-}
-
-func (*SearchEngine) Index(indexName string) error {
-	res, err := ESClient.Indices.Exists([]string{indexName})
-	if err != nil {
-		fmt.Println("Creating index error:", err)
+// IndexCtx is like Index but carries ctx to the request.
+func (r *SearchEngine) IndexCtx(ctx context.Context, indexName string, opts ...IndexOption) error {
+	ok, err := r.IndexExistsCtx(ctx, indexName)
+	if err != nil || ok {
 		return err
 	}
-	if res.StatusCode == 200 {
+	err = r.CreateIndexCtx(ctx, indexName, opts...)
+	// another client may have created it since
+	if e, ok := err.(*ESError); ok && e.Type == "resource_already_exists_exception" {
 		return nil
 	}
-	if res.StatusCode != 404 {
-		return fmt.Errorf("error in index existence response: %s", res.String())
-	}
-	res, err = ESClient.Indices.Create(indexName)
-	if err != nil {
-		return fmt.Errorf("cannot create index: %w", err)
-	}
-	if res.IsError() {
-		return fmt.Errorf("error in index creation response: %s", res.String())
-	}
-	return nil
+	return err
 }
 
-func (*SearchEngine) AddDoc(indexName string, data SearchEngine_Doc) (id string, err error) {
+func (r *SearchEngine) AddDoc(indexName string, data SearchEngine_Doc) (id string, err error) {
+	return r.AddDocCtx(context.Background(), indexName, data)
+}
+
+// AddDocCtx is like AddDoc but carries ctx to the request.
+func (r *SearchEngine) AddDocCtx(ctx context.Context, indexName string, data SearchEngine_Doc) (id string, err error) {
 
 	if data == nil {
 		return "", fmt.Errorf("Empty doc")
@@ -137,22 +199,18 @@ func (*SearchEngine) AddDoc(indexName string, data SearchEngine_Doc) (id string,
 		Refresh: "true",
 	}
 
-	res, err := req.Do(context.Background(), ESClient)
+	res, err := req.Do(ctx, r.Client())
 
 	if err != nil {
 		return "", fmt.Errorf("add doc request: %w", err)
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode == 404 {
-		return "", fmt.Errorf("add doc request 404")
-	}
-
 	if res.IsError() {
-		return "", fmt.Errorf("add doc response: %s", res.String())
+		return "", newESError("add doc", res)
 	}
 
-	fmt.Println("add doc response:", res)
+	r.logResponse(ctx, "add doc", res)
 	var (
 		body DocOpt
 	)
@@ -160,17 +218,17 @@ func (*SearchEngine) AddDoc(indexName string, data SearchEngine_Doc) (id string,
 	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
 		return "", fmt.Errorf("add doc decode: %w", err)
 	}
-	if err != nil {
-		fmt.Println("Add Doc error:", err)
-		return
-	}
-	defer res.Body.Close()
 	id = body.ID
 
 	return
 }
 
-func (*SearchEngine) AddDocs(indexName string, list []SearchEngine_Doc) (ids []string, err error) {
+func (r *SearchEngine) AddDocs(indexName string, list []SearchEngine_Doc) (ids []string, err error) {
+	return r.AddDocsCtx(context.Background(), indexName, list)
+}
+
+// AddDocsCtx is like AddDocs but carries ctx to the request.
+func (r *SearchEngine) AddDocsCtx(ctx context.Context, indexName string, list []SearchEngine_Doc) (ids []string, err error) {
 	for _, data := range list {
 		req := esapi.IndexRequest{
 			Index:   indexName,
@@ -178,22 +236,18 @@ func (*SearchEngine) AddDocs(indexName string, list []SearchEngine_Doc) (ids []s
 			Refresh: "true",
 		}
 
-		res, err := req.Do(context.Background(), ESClient)
+		res, err := req.Do(ctx, r.Client())
 
 		if err != nil {
 			return nil, fmt.Errorf("add doc request: %w", err)
 		}
 		defer res.Body.Close()
 
-		if res.StatusCode == 404 {
-			return nil, fmt.Errorf("add doc request 404")
-		}
-
 		if res.IsError() {
-			return nil, fmt.Errorf("add doc response: %s", res.String())
+			return nil, newESError("add doc", res)
 		}
 
-		fmt.Println("add doc response:", res)
+		r.logResponse(ctx, "add doc", res)
 		var (
 			body DocOpt
 		)
@@ -201,215 +255,160 @@ func (*SearchEngine) AddDocs(indexName string, list []SearchEngine_Doc) (ids []s
 		if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
 			return nil, fmt.Errorf("add doc decode: %w", err)
 		}
-		if err != nil {
-			fmt.Println("Add Doc error:", err)
-			continue
-		}
-		defer res.Body.Close()
 		ids = append(ids, body.ID)
 	}
 	return
 }
 
-func (*SearchEngine) BulkCreate(indexName string, list []SearchEngine_Doc) (ids []string, err error) {
+func (r *SearchEngine) BulkCreate(indexName string, list []SearchEngine_Doc) (ids []string, err error) {
+	return r.BulkCreateCtx(context.Background(), indexName, list)
+}
+
+// BulkCreateCtx is like BulkCreate but carries ctx to the request.
+//
+// ids holds the id of every document in list order. When some documents are
+// rejected err is a *BulkError whose Report tells which ones and why.
+func (r *SearchEngine) BulkCreateCtx(ctx context.Context, indexName string, list []SearchEngine_Doc) (ids []string, err error) {
 	if len(list) == 0 {
 		return nil, fmt.Errorf("Empty docs")
 	}
-	action := util.MapToJson(map[string]interface{}{
-		"index": map[string]interface{}{
-			"_index": indexName, // Elasticsearch index name
-		},
-	})
-	var buf strings.Builder
-	for _, doc := range list {
-		buf.WriteString(fmt.Sprintf("%s\n", action))
-		buf.WriteString(fmt.Sprintf("%s\n", doc.ToJSON()))
-	}
-
-	// fmt.Println("BUF:", buf.String())
-	req := esapi.BulkRequest{
-		Index:   indexName,
-		Body:    bytes.NewReader([]byte(buf.String())),
-		Refresh: "true",
-	}
-	res, err := req.Do(context.Background(), ESClient)
-	if err != nil {
-		log.Fatalf("Error executing bulk request: %s", err)
-	}
-	defer res.Body.Close()
-
-	if res.IsError() {
-		log.Fatalf("Error response: %s", res.String())
-	}
-
-	fmt.Println("response:", res)
-	var (
-		body BulkOpt
-	)
-
-	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
-		fmt.Println("error:", err)
-		return nil, fmt.Errorf("bulk insert doc decode errror: %w", err)
+	var entries []bulkEntry
+	for i, doc := range list {
+		body := doc.ToJSON()
+		if body == "" {
+			return nil, fmt.Errorf("Empty body for doc %d", i)
+		}
+		entries = append(entries, newBulkEntry("index", indexName, "", body))
 	}
 
-	defer res.Body.Close()
-
-	for _, it := range body.Items {
-		ids = append(ids, it.Index.ID)
+	report, err := r.executeBulk(ctx, "bulk create", indexName, "true", entries)
+	if report != nil {
+		ids = report.IDs()
 	}
+	return ids, err
+}
 
-	return
+func (r *SearchEngine) BulkUpdate(indexName string, list []SearchEngine_Doc) (err error) {
+	return r.BulkUpdateCtx(context.Background(), indexName, list)
 }
 
-func (*SearchEngine) BulkUpdate(indexName string, list []SearchEngine_Doc) (err error) {
+// BulkUpdateCtx is like BulkUpdate but carries ctx to the request. When some
+// documents are rejected the error is a *BulkError.
+func (r *SearchEngine) BulkUpdateCtx(ctx context.Context, indexName string, list []SearchEngine_Doc) (err error) {
 	if len(list) == 0 {
 		return fmt.Errorf("Empty docs")
 	}
 
-	var buf strings.Builder
-	for _, doc := range list {
-		action := util.MapToJson(map[string]interface{}{
-			"update": map[string]interface{}{
-				"_index": indexName,
-				"_id":    doc.GetID(),
-			},
-		})
-		doc := util.MapToJson(map[string]interface{}{
-			"doc": doc,
-		})
-		buf.WriteString(fmt.Sprintf("%s\n", action))
-		buf.WriteString(fmt.Sprintf("%s\n", doc))
-	}
-
-	fmt.Println("BUF:", buf.String())
-	req := esapi.BulkRequest{
-		Index:   indexName,
-		Body:    bytes.NewReader([]byte(buf.String())),
-		Refresh: "true",
-	}
-	res, err := req.Do(context.Background(), ESClient)
-	if err != nil {
-		log.Fatalf("Error executing bulk request: %s", err)
+	var entries []bulkEntry
+	for i, doc := range list {
+		b, err := json.Marshal(map[string]interface{}{"doc": doc})
+		if err != nil {
+			return fmt.Errorf("encode doc %d: %w", i, err)
+		}
+		entries = append(entries, newBulkEntry("update", indexName, doc.GetID(), string(b)))
 	}
-	defer res.Body.Close()
 
-	if res.IsError() {
-		log.Fatalf("Error response: %s", res.String())
-	}
+	_, err = r.executeBulk(ctx, "bulk update", indexName, "true", entries)
+	return err
+}
 
-	fmt.Println("response:", res)
-	return
+func (r *SearchEngine) UpdateDoc(indexName string, id string, fields map[string]interface{}) error {
+	return r.UpdateDocCtx(context.Background(), indexName, id, fields)
 }
 
-func (*SearchEngine) UpdateDoc(indexName string, id string, fields map[string]interface{}) error {
+// UpdateDocCtx is like UpdateDoc but carries ctx to the request.
+func (r *SearchEngine) UpdateDocCtx(ctx context.Context, indexName string, id string, fields map[string]interface{}) error {
 	if strings.TrimSpace(id) == "" || fields == nil {
 		return fmt.Errorf("Empty id or fiedls")
 	}
-	updateData := map[string]interface{}{
-		"doc": fields,
+	b, err := json.Marshal(map[string]interface{}{"doc": fields})
+	if err != nil {
+		return fmt.Errorf("encode doc: %w", err)
 	}
 
 	req := esapi.UpdateRequest{
 		Index:      indexName,
 		DocumentID: id,
-		Body:       bytes.NewReader([]byte(util.MapToJson(updateData))),
+		Body:       bytes.NewReader(b),
 	}
-	res, err := req.Do(context.Background(), ESClient)
+	res, err := req.Do(ctx, r.Client())
 	if err != nil {
 		return fmt.Errorf("update request: %w", err)
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode == 404 {
-		return fmt.Errorf("update request 404")
-	}
-
 	if res.IsError() {
-		return fmt.Errorf("update request: %s", res.String())
+		return newESError("update doc", res)
 	}
 
-	fmt.Println("update doc res:", res)
+	r.logResponse(ctx, "update doc", res)
 
 	return nil
 }
 
-func (*SearchEngine) BulkDelete(indexName string, ids []string) (err error) {
+func (r *SearchEngine) BulkDelete(indexName string, ids []string) (err error) {
+	return r.BulkDeleteCtx(context.Background(), indexName, ids)
+}
+
+// BulkDeleteCtx is like BulkDelete but carries ctx to the request. When some
+// documents cannot be deleted the error is a *BulkError.
+func (r *SearchEngine) BulkDeleteCtx(ctx context.Context, indexName string, ids []string) (err error) {
 	if len(ids) == 0 {
 		return fmt.Errorf("Empty ids")
 	}
 
-	var buf strings.Builder
+	var entries []bulkEntry
 	for _, id := range ids {
-		action := util.MapToJson(map[string]interface{}{
-			"delete": map[string]interface{}{
-				"_index": indexName,
-				"_id":    id,
-			},
-		})
-		buf.WriteString(fmt.Sprintf("%s\n", action))
-	}
-
-	// fmt.Println("BUF:", buf.String())
-	req := esapi.BulkRequest{
-		Index:   indexName,
-		Body:    bytes.NewReader([]byte(buf.String())),
-		Refresh: "true",
-	}
-	res, err := req.Do(context.Background(), ESClient)
-	if err != nil {
-		log.Fatalf("Error executing bulk request: %s", err)
-	}
-	defer res.Body.Close()
-
-	if res.IsError() {
-		log.Fatalf("Error response: %s", res.String())
+		entries = append(entries, newBulkEntry("delete", indexName, id, ""))
 	}
 
-	fmt.Println("response:", res)
+	_, err = r.executeBulk(ctx, "bulk delete", indexName, "true", entries)
+	return err
+}
 
-	return
+func (r *SearchEngine) DeleteDoc(indexName string, doc SearchEngine_Doc) error {
+	return r.DeleteDocCtx(context.Background(), indexName, doc)
 }
-func (*SearchEngine) DeleteDoc(indexName string, doc SearchEngine_Doc) error {
+
+// DeleteDocCtx is like DeleteDoc but carries ctx to the request.
+func (r *SearchEngine) DeleteDocCtx(ctx context.Context, indexName string, doc SearchEngine_Doc) error {
 	req := esapi.DeleteRequest{
 		Index:      indexName,
 		DocumentID: doc.GetID(),
 	}
 
-	res, err := req.Do(context.Background(), ESClient)
+	res, err := req.Do(ctx, r.Client())
 	if err != nil {
 		return fmt.Errorf("delete request: %w", err)
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode == 404 {
-		return fmt.Errorf("delete request 404")
-	}
-
 	if res.IsError() {
-		return fmt.Errorf("delete: response: %s", res.String())
+		return newESError("delete doc", res)
 	}
 
 	return nil
 }
 
-func (*SearchEngine) GetOne(indexName string, id string) (*Hit, error) {
+func (r *SearchEngine) GetOne(indexName string, id string) (*Hit, error) {
+	return r.GetOneCtx(context.Background(), indexName, id)
+}
+
+// GetOneCtx is like GetOne but carries ctx to the request.
+func (r *SearchEngine) GetOneCtx(ctx context.Context, indexName string, id string) (*Hit, error) {
 	req := esapi.GetRequest{
 		Index:      indexName,
 		DocumentID: id,
 	}
-	res, err := req.Do(context.Background(), ESClient)
+	res, err := req.Do(ctx, r.Client())
 	if err != nil {
 		return nil, fmt.Errorf("GetOne request: %w", err)
 	}
-	fmt.Println("res:", res)
 	defer res.Body.Close()
-
-	if res.StatusCode == 404 {
-		return nil, fmt.Errorf("GetOne request 404")
-	}
+	r.logResponse(ctx, "get one", res)
 
 	if res.IsError() {
-		return nil, fmt.Errorf("find one: response: %s", res.String())
+		return nil, newESError("get one", res)
 	}
 
 	var (
@@ -423,127 +422,136 @@ func (*SearchEngine) GetOne(indexName string, id string) (*Hit, error) {
 	return &body, nil
 }
 
-func (r *SearchEngine) QueryByIDs(indexName string, ids []string) ([]Hit, error) {
-	qt := `{
-		"query": {
-		   "ids":
-			  %s
-		}
-	}`
-	v := struct {
-		Values []string `json:"values"`
-	}{Values: ids}
-	b, err := json.Marshal(v)
+func (r *SearchEngine) QueryByIDs(indexName string, ids []string, opts ...SearchOption) (*SearchResult, error) {
+	return r.QueryByIDsCtx(context.Background(), indexName, ids, opts...)
+}
+
+// QueryByIDsCtx is like QueryByIDs but carries ctx to the request.
+func (r *SearchEngine) QueryByIDsCtx(ctx context.Context, indexName string, ids []string, opts ...SearchOption) (*SearchResult, error) {
+	q, err := searchBody(NewIdsQuery(ids...))
 	if err != nil {
 		return nil, err
 	}
-	q := fmt.Sprintf(qt, string(b))
-	fmt.Println("query:", q)
-	return r.executeQuery(indexName, q, 0, 100)
-
+	return r.executeQuery(ctx, indexName, q, newSearchOptions(opts))
 }
 
-// func (r *SearchEngine) QuerybyTermRange(indexName string, field string, values []string, t reflect.Type) ([]interface{}, error) {
-// 	qt := `{
-// 		"query": {
-// 		   "range":{
-// 			   "gte":[%s]
-// 		   }
-// 		}
-// 	}`
-// 	q := fmt.Sprintf(qt, field, strings.Join(values, ","))
-// 	return r.executeQuery(indexName, q, t)
-
-// }
+// QueryByRange returns the documents whose field lies within rg.
+func (r *SearchEngine) QueryByRange(indexName string, field string, rg Range, opts ...SearchOption) (*SearchResult, error) {
+	return r.QueryByRangeCtx(context.Background(), indexName, field, rg, opts...)
+}
 
-func (r *SearchEngine) QueryByTerms(indexName string, field string, values []string, t reflect.Type) ([]Hit, error) {
-	qt := `{
-		"query": {
-		   "terms":%s
-		}
-	}`
-	v := map[string][]string{
-		field: values,
+// QueryByRangeCtx is like QueryByRange but carries ctx to the request.
+func (r *SearchEngine) QueryByRangeCtx(ctx context.Context, indexName string, field string, rg Range, opts ...SearchOption) (*SearchResult, error) {
+	if err := r.checkFields(field); err != nil {
+		return nil, err
 	}
-	b, err := json.Marshal(v)
+	q, err := searchBody(NewBoolQuery().Filter(rg.Query(field)))
 	if err != nil {
 		return nil, err
 	}
-	q := fmt.Sprintf(qt, string(b))
-	return r.executeQuery(indexName, q, 0, 100)
-
+	return r.executeQuery(ctx, indexName, q, newSearchOptions(opts))
 }
 
-func (r *SearchEngine) FilterQuery(indexName string, filters map[string]interface{}) ([]Hit, error) {
-	qt := `{
-		"query": {
-		   "bool":{
-			"filter":%s
-		   }
-		}
-	}`
+func (r *SearchEngine) QueryByTerms(indexName string, field string, values []string, t reflect.Type, opts ...SearchOption) (*SearchResult, error) {
+	return r.QueryByTermsCtx(context.Background(), indexName, field, values, t, opts...)
+}
 
-	type term struct {
-		M map[string]interface{} `json:"term"`
+// QueryByTermsCtx is like QueryByTerms but carries ctx to the request.
+func (r *SearchEngine) QueryByTermsCtx(ctx context.Context, indexName string, field string, values []string, t reflect.Type, opts ...SearchOption) (*SearchResult, error) {
+	if err := r.checkFields(field); err != nil {
+		return nil, err
 	}
-
-	var l []term
-
-	for k, v := range filters {
-		st := struct {
-			M map[string]interface{} `json:"term"`
-		}{
-			M: map[string]interface{}{
-				k: v,
-			},
-		}
-
-		l = append(l, st)
+	terms := make([]interface{}, len(values))
+	for i, v := range values {
+		terms[i] = v
 	}
-
-	b, err := json.Marshal(l)
+	q, err := searchBody(NewTermsQuery(field, terms...))
 	if err != nil {
 		return nil, err
 	}
+	return r.executeQuery(ctx, indexName, q, newSearchOptions(opts))
+}
 
-	q := fmt.Sprintf(qt, string(b))
-	return r.executeQuery(indexName, q, 0, 100)
+// FilterQuery returns the documents matching every filter. A filter value
+// is an exact term, a Range of the field, such as Range{Gte: "now-7d/d"}, or
+// any Query.
+func (r *SearchEngine) FilterQuery(indexName string, filters map[string]interface{}, opts ...SearchOption) (*SearchResult, error) {
+	return r.FilterQueryCtx(context.Background(), indexName, filters, opts...)
+}
 
+// FilterQueryCtx is like FilterQuery but carries ctx to the request.
+func (r *SearchEngine) FilterQueryCtx(ctx context.Context, indexName string, filters map[string]interface{}, opts ...SearchOption) (*SearchResult, error) {
+	fields := make([]string, 0, len(filters))
+	for k := range filters {
+		fields = append(fields, k)
+	}
+	if err := r.checkFields(fields...); err != nil {
+		return nil, err
+	}
+	q, err := searchBody(filterQuery(filters))
+	if err != nil {
+		return nil, err
+	}
+	return r.executeQuery(ctx, indexName, q, newSearchOptions(opts))
 }
 
-func (r *SearchEngine) MultiQuery(indexName string, fields []string, text string, t reflect.Type) ([]Hit, error) {
-	qt := `{
-		"query": {
-			"multi_match": %s
+// filterQuery turns FilterQuery filters into a bool filter query, in field
+// order. A Range value becomes a range clause, a Query is used as is and
+// anything else is an exact term.
+func filterQuery(filters map[string]interface{}) *BoolQuery {
+	fields := make([]string, 0, len(filters))
+	for k := range filters {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+
+	b := NewBoolQuery()
+	for _, field := range fields {
+		switch v := filters[field].(type) {
+		case Range:
+			b.Filter(v.Query(field))
+		case *Range:
+			b.Filter(v.Query(field))
+		case Query:
+			b.Filter(v)
+		default:
+			b.Filter(NewTermQuery(field, v))
 		}
-	}`
+	}
+	return b
+}
 
-	v := query_multiMatch{Query: text, Fields: fields}
-	b, err := json.Marshal(v)
+func (r *SearchEngine) MultiQuery(indexName string, fields []string, text string, t reflect.Type, opts ...SearchOption) (*SearchResult, error) {
+	return r.MultiQueryCtx(context.Background(), indexName, fields, text, t, opts...)
+}
+
+// MultiQueryCtx is like MultiQuery but carries ctx to the request.
+func (r *SearchEngine) MultiQueryCtx(ctx context.Context, indexName string, fields []string, text string, t reflect.Type, opts ...SearchOption) (*SearchResult, error) {
+	if err := r.checkFields(fields...); err != nil {
+		return nil, err
+	}
+	mm := NewMultiMatchQuery(text, fields...).AutoGenerateSynonymsPhraseQuery(false).FuzzyTranspositions(false)
+	q, err := searchBody(mm)
 	if err != nil {
 		return nil, err
 	}
-	q := fmt.Sprintf(qt, string(b))
-
-	return r.executeQuery(indexName, q, 0, 100)
+	return r.executeQuery(ctx, indexName, q, newSearchOptions(opts))
 }
 
-func (r *SearchEngine) Query2(indexName string, value string, text string) ([]Hit, error) {
-
-	qt := `{
-		"query": {
-			"match": {
-				"%s": {
-					"query":"%s",
-					"fuzziness":"AUTO",
-					"operator":"AND"
-				}
-			}
-		}
-	}`
+func (r *SearchEngine) Query2(indexName string, value string, text string, opts ...SearchOption) (*SearchResult, error) {
+	return r.Query2Ctx(context.Background(), indexName, value, text, opts...)
+}
 
-	q := fmt.Sprintf(qt, value, text)
-	return r.executeQuery(indexName, q, 0, 100)
+// Query2Ctx is like Query2 but carries ctx to the request.
+func (r *SearchEngine) Query2Ctx(ctx context.Context, indexName string, value string, text string, opts ...SearchOption) (*SearchResult, error) {
+	if err := r.checkFields(value); err != nil {
+		return nil, err
+	}
+	q, err := searchBody(NewMatchQuery(value, text).Fuzziness("AUTO").Operator("AND"))
+	if err != nil {
+		return nil, err
+	}
+	return r.executeQuery(ctx, indexName, q, newSearchOptions(opts))
 }
 
 /*
@@ -562,38 +570,42 @@ func (r *SearchEngine) Query2(indexName string, value string, text string) ([]Hi
 		"boost": 1
 	}
 */
-func (r *SearchEngine) QueryWithFilter(indexName string, fields []string, text string, filter map[string]string) ([]Hit, error) {
-	qt := `{
-		"query": {
-			"bool":{
-				"must":[
-					{
-						"multi_match":%s
-					}
-				],
-				"filter":{
-					"term": %s
-				}
-			}
-		}
-	}`
-	mm := query_multiMatch{Query: text, Fields: fields, AutoGenerateSynonymsPhraseQuery: true, FuzzyTranspositions: true}
-	mmb, err := json.Marshal(mm)
-	if err != nil {
+func (r *SearchEngine) QueryWithFilter(indexName string, fields []string, text string, filter map[string]string, opts ...SearchOption) (*SearchResult, error) {
+	return r.QueryWithFilterCtx(context.Background(), indexName, fields, text, filter, opts...)
+}
+
+// QueryWithFilterCtx is like QueryWithFilter but carries ctx to the request.
+func (r *SearchEngine) QueryWithFilterCtx(ctx context.Context, indexName string, fields []string, text string, filter map[string]string, opts ...SearchOption) (*SearchResult, error) {
+	keys := make([]string, 0, len(filter))
+	for k := range filter {
+		keys = append(keys, k)
+	}
+	if err := r.checkFields(append(keys, fields...)...); err != nil {
 		return nil, err
 	}
-	ftb, err := json.Marshal(filter)
+	filters := make(map[string]interface{}, len(filter))
+	for k, v := range filter {
+		filters[k] = v
+	}
+	b := filterQuery(filters).Must(
+		NewMultiMatchQuery(text, fields...).AutoGenerateSynonymsPhraseQuery(true).FuzzyTranspositions(true),
+	)
+	q, err := searchBody(b)
 	if err != nil {
 		return nil, err
 	}
-
-	q := fmt.Sprintf(qt, string(mmb), string(ftb))
-
-	return r.executeQuery(indexName, q, 0, 100)
+	return r.executeQuery(ctx, indexName, q, newSearchOptions(opts))
 }
 
 func (r *SearchEngine) QueryFieldById(indexName string, ids, fields []string) ([]Hit, error) {
-	qt := `{"docs":%s}`
+	return r.QueryFieldByIdCtx(context.Background(), indexName, ids, fields)
+}
+
+// QueryFieldByIdCtx is like QueryFieldById but carries ctx to the request.
+func (r *SearchEngine) QueryFieldByIdCtx(ctx context.Context, indexName string, ids, fields []string) ([]Hit, error) {
+	if err := r.checkFields(fields...); err != nil {
+		return nil, err
+	}
 	type doc struct {
 		Index  string   `json:"_index"`
 		ID     string   `json:"_id"`
@@ -603,32 +615,28 @@ func (r *SearchEngine) QueryFieldById(indexName string, ids, fields []string) ([
 	for _, id := range ids {
 		v = append(v, doc{Index: indexName, ID: id, Source: fields})
 	}
-	b, err := json.Marshal(v)
+	b, err := json.Marshal(map[string]interface{}{"docs": v})
 	if err != nil {
 		return nil, err
 	}
-	q := fmt.Sprintf(qt, string(b))
+	q := string(b)
 	req := esapi.MgetRequest{
 		Body: strings.NewReader(q),
 	}
-	fmt.Println("executing query:", q)
+	r.logRequest(ctx, "query", indexName, q)
 
-	res, err := req.Do(context.Background(), ESClient)
+	res, err := req.Do(ctx, r.Client())
 
 	if err != nil {
 		return nil, fmt.Errorf("Query request: %w", err)
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode == 404 {
-		return nil, fmt.Errorf("Query request 404")
-	}
-
 	if res.IsError() {
-		return nil, fmt.Errorf("Query response: %s", res.String())
+		return nil, newESError("mget", res)
 	}
 
-	fmt.Println("search result:", res)
+	r.logResponse(ctx, "query", res)
 	type docRes struct {
 		Docs []Hit `json:"docs"`
 	}
@@ -643,31 +651,37 @@ func (r *SearchEngine) QueryFieldById(indexName string, ids, fields []string) ([
 	return d.Docs, nil
 }
 
-func (r *SearchEngine) executeQuery(indexName string, q string, from, size int) ([]Hit, error) {
+func (r *SearchEngine) executeQuery(ctx context.Context, indexName string, q string, o *searchOptions) (*SearchResult, error) {
+	q, err := o.merge(q)
+	if err != nil {
+		return nil, err
+	}
 	req := esapi.SearchRequest{
-		Index: []string{indexName},
-		Body:  strings.NewReader(q),
-		From:  &from,
-		Size:  &size,
+		Index:          []string{indexName},
+		Body:           strings.NewReader(q),
+		From:           &o.from,
+		Size:           &o.size,
+		TrackTotalHits: o.trackTotalHits,
 	}
-	fmt.Println("executing query:", q)
+	return r.doSearch(ctx, req, indexName, q)
+}
+
+// doSearch sends req, whose body is q, and decodes the response.
+func (r *SearchEngine) doSearch(ctx context.Context, req esapi.Request, indexName string, q string) (*SearchResult, error) {
+	r.logRequest(ctx, "query", indexName, q)
 
-	res, err := req.Do(context.Background(), ESClient)
+	res, err := req.Do(ctx, r.Client())
 
 	if err != nil {
 		return nil, fmt.Errorf("Query request: %w", err)
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode == 404 {
-		return nil, fmt.Errorf("Query request 404")
-	}
-
 	if res.IsError() {
-		return nil, fmt.Errorf("Query response: %s", res.String())
+		return nil, newESError("query", res)
 	}
 
-	fmt.Println("search result:", res)
+	r.logResponse(ctx, "query", res)
 	var (
 		body queryResponse
 	)
@@ -676,7 +690,7 @@ func (r *SearchEngine) executeQuery(indexName string, q string, from, size int)
 		return nil, fmt.Errorf("Query decode: %w", err)
 	}
 
-	return body.Each(), nil
+	return body.result(), nil
 }
 
 func checkInterface(typ reflect.Type, funcname string) bool {
@@ -684,6 +698,15 @@ func checkInterface(typ reflect.Type, funcname string) bool {
 	return b
 }
 
+func (r *queryResponse) result() *SearchResult {
+	res := &SearchResult{PitID: r.PitID, ScrollID: r.ScrollID, Took: r.Took, TimedOut: r.TimedOut, Shards: r.Shards, Hits: r.Each(), Aggregations: r.Aggregations}
+	if r.Hits != nil {
+		res.Total = r.Hits.Total
+		res.MaxScore = r.Hits.MaxScore
+	}
+	return res
+}
+
 func (r *queryResponse) Each() []Hit {
 
 	if r.Hits == nil || r.Hits.Hits == nil || len(r.Hits.Hits) == 0 {
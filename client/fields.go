@@ -0,0 +1,50 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WithAllowedFields limits the field names the query methods accept, so
+// that field names taken from user input cannot reach arbitrary fields. A
+// name ending in "*" allows every field with that prefix, e.g. "instructions.*".
+// Boosts such as "title^2" are checked without the boost. Queries built with
+// the New*Query constructors are not checked.
+func WithAllowedFields(fields ...string) Option {
+	return func(o *engineOptions) {
+		o.fields = append(o.fields, fields...)
+	}
+}
+
+// checkFields returns an ErrFieldNotAllowed error for the first empty name
+// or, with an allow-list, the first name outside it.
+func (r *SearchEngine) checkFields(fields ...string) error {
+	for _, f := range fields {
+		name := f
+		if i := strings.LastIndexByte(name, '^'); i > 0 {
+			name = name[:i]
+		}
+		if name == "" {
+			return fmt.Errorf("%w: empty field name", ErrFieldNotAllowed)
+		}
+		if r == nil || r.fields == nil {
+			continue
+		}
+		if !fieldAllowed(r.fields, name) {
+			return fmt.Errorf("%w: %q", ErrFieldNotAllowed, f)
+		}
+	}
+	return nil
+}
+
+func fieldAllowed(allowed []string, name string) bool {
+	for _, a := range allowed {
+		if a == name {
+			return true
+		}
+		if p, ok := strings.CutSuffix(a, "*"); ok && strings.HasPrefix(name, p) && p != "" {
+			return true
+		}
+	}
+	return false
+}
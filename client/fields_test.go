@@ -0,0 +1,145 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// newCaptureEngine returns an engine whose requests never leave the process;
+// the last search body is stored in *body.
+func newCaptureEngine(t testing.TB, body *[]byte, opts ...Option) *SearchEngine {
+	tr := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		*body = nil
+		if r.Body != nil {
+			*body, _ = io.ReadAll(r.Body)
+		}
+		h := http.Header{}
+		h.Set("X-Elastic-Product", "Elasticsearch")
+		h.Set("Content-Type", "application/json")
+		return &http.Response{
+			StatusCode: 200,
+			Header:     h,
+			Body:       io.NopCloser(strings.NewReader(`{"hits":{"hits":[]}}`)),
+		}, nil
+	})
+	s, err := NewSearchEngine(append([]Option{WithAddresses("http://es.invalid:9200"), WithTransport(tr)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestAllowedFields(t *testing.T) {
+	var body []byte
+	s := newCaptureEngine(t, &body, WithAllowedFields("title", "instructions.*"))
+
+	if _, err := s.Query2("recipe_data", "user_id", "1"); !errors.Is(err, ErrFieldNotAllowed) {
+		t.Errorf("Query2 on user_id: %v", err)
+	}
+	if body != nil {
+		t.Error("a rejected query must not be sent")
+	}
+	if _, err := s.MultiQuery("recipe_data", []string{"title^2", "instructions.steps"}, "apple", nil); err != nil {
+		t.Errorf("MultiQuery on allowed fields: %v", err)
+	}
+	if _, err := s.FilterQuery("recipe_data", map[string]interface{}{"title": "x", "secret": "y"}); !errors.Is(err, ErrFieldNotAllowed) {
+		t.Errorf("FilterQuery with secret: %v", err)
+	}
+	if _, err := s.QueryWithFilter("recipe_data", []string{"title"}, "apple", map[string]string{"instructions": "x"}); !errors.Is(err, ErrFieldNotAllowed) {
+		t.Errorf("QueryWithFilter on instructions: %v", err)
+	}
+}
+
+// jsonText is s as it reads after a JSON round trip, with invalid UTF-8
+// replaced.
+func jsonText(s string) string {
+	b, _ := json.Marshal(s)
+	json.Unmarshal(b, &s)
+	return s
+}
+
+func FuzzQuery2(f *testing.F) {
+	f.Add("title", "apple pie")
+	f.Add("title", `apple", "fuzziness": "0"}}, "size": 10000, "x": {"y": "`)
+	f.Add(`title": {"query": "x"}}, "script": {"source": "`, "pie")
+	f.Add("instructions.steps", "\\\"\n\t </script>")
+	f.Add("title", "\xff\xfe")
+
+	var body []byte
+	s := newCaptureEngine(f, &body)
+	f.Fuzz(func(t *testing.T, field, text string) {
+		body = nil
+		_, err := s.Query2("recipe_data", field, text)
+		if field == "" {
+			if !errors.Is(err, ErrFieldNotAllowed) {
+				t.Fatalf("empty field %q: %v", field, err)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var q struct {
+			Query struct {
+				Match map[string]struct {
+					Query     string `json:"query"`
+					Fuzziness string `json:"fuzziness"`
+					Operator  string `json:"operator"`
+				} `json:"match"`
+			} `json:"query"`
+		}
+		dec := json.NewDecoder(strings.NewReader(string(body)))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&q); err != nil {
+			t.Fatalf("body %s: %v", body, err)
+		}
+		m, ok := q.Query.Match[jsonText(field)]
+		if len(q.Query.Match) != 1 || !ok {
+			t.Fatalf("field %q escaped its place: %s", field, body)
+		}
+		if m.Query != jsonText(text) || m.Fuzziness != "AUTO" || m.Operator != "AND" {
+			t.Fatalf("text %q changed the query: %s", text, body)
+		}
+	})
+}
+
+func FuzzFilterQuery(f *testing.F) {
+	f.Add("meal", "dinner")
+	f.Add(`meal":"x"}},{"match_all":{`, `"}}]}}, "size": 1}`)
+
+	var body []byte
+	s := newCaptureEngine(f, &body)
+	f.Fuzz(func(t *testing.T, field, value string) {
+		_, err := s.FilterQuery("recipe_data", map[string]interface{}{field: value})
+		if err != nil {
+			if !errors.Is(err, ErrFieldNotAllowed) {
+				t.Fatal(err)
+			}
+			return
+		}
+		var q struct {
+			Query struct {
+				Bool struct {
+					Filter []map[string]map[string]string `json:"filter"`
+				} `json:"bool"`
+			} `json:"query"`
+		}
+		dec := json.NewDecoder(strings.NewReader(string(body)))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&q); err != nil {
+			t.Fatalf("body %s: %v", body, err)
+		}
+		if fl := q.Query.Bool.Filter; len(fl) != 1 || len(fl[0]["term"]) != 1 ||
+			fl[0]["term"][jsonText(field)] != jsonText(value) {
+			t.Fatalf("filter %q=%q escaped its place: %s", field, value, body)
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package client
+
+// Highlight asks for snippets of the matched text of each hit, returned in
+// Hit.Highlight by field name:
+//
+//	h := NewHighlight().Fields("title", "instructions.steps").
+//		PreTags("<em>").PostTags("</em>").FragmentSize(80)
+//	res, err := engine.Search("recipe_data", q, WithHighlight(h))
+type Highlight struct {
+	settings highlightSettings
+	fields   []*HighlightField
+}
+
+// HighlightField overrides the Highlight settings for one field.
+type HighlightField struct {
+	name     string
+	settings highlightSettings
+}
+
+type highlightSettings struct {
+	preTags           []string
+	postTags          []string
+	fragmentSize      *int
+	numberOfFragments *int
+	typ               string
+	requireMatch      *bool
+}
+
+func (s *highlightSettings) source(m map[string]interface{}) map[string]interface{} {
+	if s.preTags != nil {
+		m["pre_tags"] = s.preTags
+	}
+	if s.postTags != nil {
+		m["post_tags"] = s.postTags
+	}
+	if s.fragmentSize != nil {
+		m["fragment_size"] = *s.fragmentSize
+	}
+	if s.numberOfFragments != nil {
+		m["number_of_fragments"] = *s.numberOfFragments
+	}
+	if s.typ != "" {
+		m["type"] = s.typ
+	}
+	if s.requireMatch != nil {
+		m["require_field_match"] = *s.requireMatch
+	}
+	return m
+}
+
+func NewHighlight() *Highlight {
+	return &Highlight{}
+}
+
+// Fields highlights the named fields with the shared settings. Names may
+// contain wildcards.
+func (h *Highlight) Fields(names ...string) *Highlight {
+	for _, n := range names {
+		h.fields = append(h.fields, NewHighlightField(n))
+	}
+	return h
+}
+
+// Field highlights f with its own settings.
+func (h *Highlight) Field(f *HighlightField) *Highlight {
+	h.fields = append(h.fields, f)
+	return h
+}
+
+// PreTags and PostTags wrap the matched terms, <em> and </em> by default.
+func (h *Highlight) PreTags(tags ...string) *Highlight {
+	h.settings.preTags = tags
+	return h
+}
+
+func (h *Highlight) PostTags(tags ...string) *Highlight {
+	h.settings.postTags = tags
+	return h
+}
+
+// FragmentSize is the length of a snippet in characters, 100 by default.
+func (h *Highlight) FragmentSize(n int) *Highlight {
+	h.settings.fragmentSize = &n
+	return h
+}
+
+// NumberOfFragments is the maximum number of snippets per field, 5 by
+// default. 0 returns the whole field value highlighted.
+func (h *Highlight) NumberOfFragments(n int) *Highlight {
+	h.settings.numberOfFragments = &n
+	return h
+}
+
+// Type selects the highlighter: "unified" (the default), "plain" or "fvh".
+func (h *Highlight) Type(t string) *Highlight {
+	h.settings.typ = t
+	return h
+}
+
+// RequireFieldMatch only highlights fields the query searched in. It is on
+// by default.
+func (h *Highlight) RequireFieldMatch(b bool) *Highlight {
+	h.settings.requireMatch = &b
+	return h
+}
+
+func (h *Highlight) Source() map[string]interface{} {
+	fields := make([]interface{}, 0, len(h.fields))
+	for _, f := range h.fields {
+		fields = append(fields, map[string]interface{}{f.name: f.settings.source(map[string]interface{}{})})
+	}
+	// a list keeps the field order, which decides precedence
+	return h.settings.source(map[string]interface{}{"fields": fields})
+}
+
+func NewHighlightField(name string) *HighlightField {
+	return &HighlightField{name: name}
+}
+
+func (f *HighlightField) PreTags(tags ...string) *HighlightField {
+	f.settings.preTags = tags
+	return f
+}
+
+func (f *HighlightField) PostTags(tags ...string) *HighlightField {
+	f.settings.postTags = tags
+	return f
+}
+
+func (f *HighlightField) FragmentSize(n int) *HighlightField {
+	f.settings.fragmentSize = &n
+	return f
+}
+
+func (f *HighlightField) NumberOfFragments(n int) *HighlightField {
+	f.settings.numberOfFragments = &n
+	return f
+}
+
+func (f *HighlightField) Type(t string) *HighlightField {
+	f.settings.typ = t
+	return f
+}
+
+// WithHighlight returns highlighted snippets in Hit.Highlight.
+func WithHighlight(h *Highlight) SearchOption {
+	return func(o *searchOptions) {
+		o.set("highlight", h.Source())
+	}
+}
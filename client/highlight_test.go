@@ -0,0 +1,35 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		var body struct {
+			Highlight json.RawMessage `json:"highlight"`
+		}
+		json.Unmarshal(b, &body)
+		want := `{"fields":[{"title":{}},{"instructions.steps":{"fragment_size":150,"number_of_fragments":3}}],` +
+			`"post_tags":["\u003c/mark\u003e"],"pre_tags":["\u003cmark\u003e"],"type":"unified"}`
+		if string(body.Highlight) != want {
+			t.Errorf("highlight\ngot  %s\nwant %s", body.Highlight, want)
+		}
+		w.Write([]byte(`{"hits":{"hits":[{"_id":"1","_source":{},"highlight":{"title":["<mark>apple</mark> pie"]}}]}}`))
+	})
+
+	h := NewHighlight().Fields("title").
+		Field(NewHighlightField("instructions.steps").FragmentSize(150).NumberOfFragments(3)).
+		PreTags("<mark>").PostTags("</mark>").Type("unified")
+	res, err := s.Search("recipe_data", NewMatchQuery("title", "apple"), WithHighlight(h))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Hits[0].Highlight["title"]; len(got) != 1 || got[0] != "<mark>apple</mark> pie" {
+		t.Errorf("unexpected highlight %v", res.Hits[0].Highlight)
+	}
+}
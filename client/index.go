@@ -0,0 +1,393 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// IndexOption configures an index created by CreateIndex or Index.
+type IndexOption func(*indexConfig)
+
+type indexConfig struct {
+	settings map[string]interface{}
+	mapping  *Mapping
+	doc      interface{} // mapped when the index is created
+}
+
+func (c *indexConfig) set(key string, v interface{}) {
+	if c.settings == nil {
+		c.settings = map[string]interface{}{}
+	}
+	c.settings[key] = v
+}
+
+// WithShards sets the number of primary shards. It cannot change once the
+// index exists.
+func WithShards(n int) IndexOption {
+	return func(c *indexConfig) {
+		c.set("number_of_shards", n)
+	}
+}
+
+// WithReplicas sets the number of copies of each primary shard.
+func WithReplicas(n int) IndexOption {
+	return func(c *indexConfig) {
+		c.set("number_of_replicas", n)
+	}
+}
+
+// WithAnalysis defines the analyzers, tokenizers and filters of the index,
+// as the "analysis" settings object:
+//
+//	WithAnalysis(map[string]interface{}{
+//		"analyzer": map[string]interface{}{
+//			"recipe": map[string]interface{}{
+//				"tokenizer": "standard",
+//				"filter":    []string{"lowercase", "asciifolding"},
+//			},
+//		},
+//	})
+func WithAnalysis(analysis map[string]interface{}) IndexOption {
+	return func(c *indexConfig) {
+		c.set("analysis", analysis)
+	}
+}
+
+// WithIndexSetting sets any other index setting, such as "refresh_interval".
+func WithIndexSetting(key string, v interface{}) IndexOption {
+	return func(c *indexConfig) {
+		c.set(key, v)
+	}
+}
+
+// WithMapping creates the index with the mapping m.
+func WithMapping(m *Mapping) IndexOption {
+	return func(c *indexConfig) {
+		c.mapping, c.doc = m, nil
+	}
+}
+
+// WithMappingOf creates the index with the mapping MappingOf derives from
+// the struct doc.
+func WithMappingOf(doc interface{}) IndexOption {
+	return func(c *indexConfig) {
+		c.mapping, c.doc = nil, doc
+	}
+}
+
+func (c *indexConfig) body() (string, error) {
+	m := c.mapping
+	if c.doc != nil {
+		var err error
+		if m, err = MappingOf(c.doc); err != nil {
+			return "", err
+		}
+	}
+	body := map[string]interface{}{}
+	if c.settings != nil {
+		body["settings"] = map[string]interface{}{"index": c.settings}
+	}
+	if m != nil {
+		body["mappings"] = m
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return "", fmt.Errorf("encode index: %w", err)
+	}
+	return string(b), nil
+}
+
+// CreateIndex creates indexName with the given settings and mapping. It
+// fails if the index exists; Index creates it only when missing.
+func (r *SearchEngine) CreateIndex(indexName string, opts ...IndexOption) error {
+	return r.CreateIndexCtx(context.Background(), indexName, opts...)
+}
+
+// CreateIndexCtx is like CreateIndex but carries ctx to the request.
+func (r *SearchEngine) CreateIndexCtx(ctx context.Context, indexName string, opts ...IndexOption) error {
+	c := &indexConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	body, err := c.body()
+	if err != nil {
+		return err
+	}
+	r.logRequest(ctx, "create index", indexName, body)
+
+	req := esapi.IndicesCreateRequest{
+		Index: indexName,
+		Body:  strings.NewReader(body),
+	}
+	res, err := req.Do(ctx, r.Client())
+	if err != nil {
+		return fmt.Errorf("create index request: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return newESError("create index", res)
+	}
+	r.logResponse(ctx, "create index", res)
+	return nil
+}
+
+// WithRefreshInterval sets how often new writes become searchable. A
+// negative d turns refreshes off, which speeds up bulk loads.
+func WithRefreshInterval(d time.Duration) IndexOption {
+	return func(c *indexConfig) {
+		if d < 0 {
+			c.set("refresh_interval", "-1")
+			return
+		}
+		if d%time.Second == 0 {
+			c.set("refresh_interval", fmt.Sprintf("%ds", d/time.Second))
+			return
+		}
+		c.set("refresh_interval", fmt.Sprintf("%dms", d.Milliseconds()))
+	}
+}
+
+// AckResult is the response of an index operation the cluster acknowledges.
+type AckResult struct {
+	Acknowledged       bool `json:"acknowledged"`        // the cluster state changed
+	ShardsAcknowledged bool `json:"shards_acknowledged"` // the shards started in time, for open and close
+}
+
+// ShardsResult is the response of an index operation run on each shard.
+type ShardsResult struct {
+	Total      int            `json:"total"`
+	Successful int            `json:"successful"`
+	Failed     int            `json:"failed"`
+	Failures   []ShardFailure `json:"failures,omitempty"`
+}
+
+// ShardFailure is the error of one shard.
+type ShardFailure struct {
+	Index  string     `json:"index"`
+	Shard  int        `json:"shard"`
+	Status string     `json:"status"`
+	Reason ErrorCause `json:"reason"`
+}
+
+// IndexSettings are the settings of one index.
+type IndexSettings struct {
+	Index           string
+	Shards          int
+	Replicas        int
+	RefreshInterval string // "" for the default of 1s
+
+	// Settings holds every setting by its flat name, such as
+	// "index.refresh_interval". The cluster reports all values as strings.
+	Settings map[string]interface{}
+}
+
+// DeleteIndex deletes indexName and all its documents.
+func (r *SearchEngine) DeleteIndex(indexName string) (*AckResult, error) {
+	return r.DeleteIndexCtx(context.Background(), indexName)
+}
+
+// DeleteIndexCtx is like DeleteIndex but carries ctx to the request.
+func (r *SearchEngine) DeleteIndexCtx(ctx context.Context, indexName string) (*AckResult, error) {
+	return r.ackRequest(ctx, "delete index", indexName, esapi.IndicesDeleteRequest{Index: []string{indexName}})
+}
+
+// CloseIndex closes indexName: it keeps its data but takes no reads or
+// writes until OpenIndex.
+func (r *SearchEngine) CloseIndex(indexName string) (*AckResult, error) {
+	return r.CloseIndexCtx(context.Background(), indexName)
+}
+
+// CloseIndexCtx is like CloseIndex but carries ctx to the request.
+func (r *SearchEngine) CloseIndexCtx(ctx context.Context, indexName string) (*AckResult, error) {
+	return r.ackRequest(ctx, "close index", indexName, esapi.IndicesCloseRequest{Index: []string{indexName}})
+}
+
+// OpenIndex reopens a closed index.
+func (r *SearchEngine) OpenIndex(indexName string) (*AckResult, error) {
+	return r.OpenIndexCtx(context.Background(), indexName)
+}
+
+// OpenIndexCtx is like OpenIndex but carries ctx to the request.
+func (r *SearchEngine) OpenIndexCtx(ctx context.Context, indexName string) (*AckResult, error) {
+	return r.ackRequest(ctx, "open index", indexName, esapi.IndicesOpenRequest{Index: []string{indexName}})
+}
+
+// Refresh makes all writes to indexName searchable now.
+func (r *SearchEngine) Refresh(indexName string) (*ShardsResult, error) {
+	return r.RefreshCtx(context.Background(), indexName)
+}
+
+// RefreshCtx is like Refresh but carries ctx to the request.
+func (r *SearchEngine) RefreshCtx(ctx context.Context, indexName string) (*ShardsResult, error) {
+	return r.shardsRequest(ctx, "refresh", indexName, esapi.IndicesRefreshRequest{Index: []string{indexName}})
+}
+
+// Flush writes the buffered operations of indexName to disk.
+func (r *SearchEngine) Flush(indexName string) (*ShardsResult, error) {
+	return r.FlushCtx(context.Background(), indexName)
+}
+
+// FlushCtx is like Flush but carries ctx to the request.
+func (r *SearchEngine) FlushCtx(ctx context.Context, indexName string) (*ShardsResult, error) {
+	return r.shardsRequest(ctx, "flush", indexName, esapi.IndicesFlushRequest{Index: []string{indexName}})
+}
+
+// ForceMerge merges the segments of each shard of indexName down to
+// maxSegments, or as the cluster sees fit when maxSegments is 0. It is meant
+// for indices no longer written to, and blocks until the merge ends.
+func (r *SearchEngine) ForceMerge(indexName string, maxSegments int) (*ShardsResult, error) {
+	return r.ForceMergeCtx(context.Background(), indexName, maxSegments)
+}
+
+// ForceMergeCtx is like ForceMerge but carries ctx to the request.
+func (r *SearchEngine) ForceMergeCtx(ctx context.Context, indexName string, maxSegments int) (*ShardsResult, error) {
+	req := esapi.IndicesForcemergeRequest{Index: []string{indexName}}
+	if maxSegments > 0 {
+		req.MaxNumSegments = &maxSegments
+	}
+	return r.shardsRequest(ctx, "force merge", indexName, req)
+}
+
+// ClearCache empties the query, request and fielddata caches of indexName.
+func (r *SearchEngine) ClearCache(indexName string) (*ShardsResult, error) {
+	return r.ClearCacheCtx(context.Background(), indexName)
+}
+
+// ClearCacheCtx is like ClearCache but carries ctx to the request.
+func (r *SearchEngine) ClearCacheCtx(ctx context.Context, indexName string) (*ShardsResult, error) {
+	return r.shardsRequest(ctx, "clear cache", indexName, esapi.IndicesClearCacheRequest{Index: []string{indexName}})
+}
+
+// GetSettings returns the settings of the indices matching indexName, which
+// may be an alias or contain wildcards, sorted by index.
+func (r *SearchEngine) GetSettings(indexName string) ([]IndexSettings, error) {
+	return r.GetSettingsCtx(context.Background(), indexName)
+}
+
+// GetSettingsCtx is like GetSettings but carries ctx to the request.
+func (r *SearchEngine) GetSettingsCtx(ctx context.Context, indexName string) ([]IndexSettings, error) {
+	if indexName == "" {
+		return nil, ErrNoIndexName
+	}
+	flat := true
+	req := esapi.IndicesGetSettingsRequest{Index: []string{indexName}, FlatSettings: &flat}
+	res, err := req.Do(ctx, r.Client())
+	if err != nil {
+		return nil, fmt.Errorf("get settings request: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, newESError("get settings", res)
+	}
+	r.logResponse(ctx, "get settings", res)
+
+	var body map[string]struct {
+		Settings map[string]interface{} `json:"settings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("get settings decode: %w", err)
+	}
+	list := make([]IndexSettings, 0, len(body))
+	for index, v := range body {
+		s := IndexSettings{Index: index, Settings: v.Settings}
+		s.Shards, _ = strconv.Atoi(fmt.Sprint(v.Settings["index.number_of_shards"]))
+		s.Replicas, _ = strconv.Atoi(fmt.Sprint(v.Settings["index.number_of_replicas"]))
+		s.RefreshInterval, _ = v.Settings["index.refresh_interval"].(string)
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Index < list[j].Index })
+	return list, nil
+}
+
+// UpdateSettings changes the dynamic settings of indexName, given as
+// WithReplicas, WithRefreshInterval and WithIndexSetting. Mapping options
+// are ignored, and so are shards, which are fixed; with nothing left to
+// change UpdateSettings returns ErrNoSettings. For a bulk load:
+//
+//	engine.UpdateSettings("recipe_data", WithReplicas(0), WithRefreshInterval(-1))
+//	// load
+//	engine.UpdateSettings("recipe_data", WithReplicas(1), WithRefreshInterval(time.Second))
+func (r *SearchEngine) UpdateSettings(indexName string, opts ...IndexOption) (*AckResult, error) {
+	return r.UpdateSettingsCtx(context.Background(), indexName, opts...)
+}
+
+// UpdateSettingsCtx is like UpdateSettings but carries ctx to the request.
+func (r *SearchEngine) UpdateSettingsCtx(ctx context.Context, indexName string, opts ...IndexOption) (*AckResult, error) {
+	c := &indexConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	delete(c.settings, "number_of_shards")
+	if len(c.settings) == 0 {
+		return nil, ErrNoSettings
+	}
+	b, err := json.Marshal(map[string]interface{}{"index": c.settings})
+	if err != nil {
+		return nil, fmt.Errorf("encode settings: %w", err)
+	}
+	r.logRequest(ctx, "update settings", indexName, string(b))
+	req := esapi.IndicesPutSettingsRequest{
+		Index: []string{indexName},
+		Body:  strings.NewReader(string(b)),
+	}
+	return r.ackRequest(ctx, "update settings", indexName, req)
+}
+
+// ackRequest sends req for the index operation op and decodes the
+// acknowledgement.
+func (r *SearchEngine) ackRequest(ctx context.Context, op, indexName string, req esapi.Request) (*AckResult, error) {
+	if indexName == "" {
+		return nil, ErrNoIndexName
+	}
+	res, err := req.Do(ctx, r.Client())
+	if err != nil {
+		return nil, fmt.Errorf("%s request: %w", op, err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, newESError(op, res)
+	}
+	r.logResponse(ctx, op, res)
+
+	var ack AckResult
+	if err := json.NewDecoder(res.Body).Decode(&ack); err != nil {
+		return nil, fmt.Errorf("%s decode: %w", op, err)
+	}
+	return &ack, nil
+}
+
+// shardsRequest sends req for the index operation op and decodes the shard
+// counts. Failed shards are returned as a *ShardFailureError along with the
+// result.
+func (r *SearchEngine) shardsRequest(ctx context.Context, op, indexName string, req esapi.Request) (*ShardsResult, error) {
+	if indexName == "" {
+		return nil, ErrNoIndexName
+	}
+	res, err := req.Do(ctx, r.Client())
+	if err != nil {
+		return nil, fmt.Errorf("%s request: %w", op, err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, newESError(op, res)
+	}
+	r.logResponse(ctx, op, res)
+
+	var body struct {
+		Shards ShardsResult `json:"_shards"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("%s decode: %w", op, err)
+	}
+	if body.Shards.Failed > 0 {
+		return &body.Shards, &ShardFailureError{Op: op, Result: body.Shards}
+	}
+	return &body.Shards, nil
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestIndexLifecycle(t *testing.T) {
+	var settings string
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodDelete && r.URL.Path == "/missing":
+			w.WriteHeader(404)
+			w.Write([]byte(`{"error":{"type":"index_not_found_exception","reason":"no such index [missing]","index":"missing"},"status":404}`))
+		case r.Method == http.MethodDelete, r.URL.Path == "/recipe_data/_close", r.URL.Path == "/recipe_data/_open":
+			w.Write([]byte(`{"acknowledged":true,"shards_acknowledged":true}`))
+		case r.URL.Path == "/recipe_data/_refresh", r.URL.Path == "/recipe_data/_cache/clear":
+			w.Write([]byte(`{"_shards":{"total":2,"successful":2,"failed":0}}`))
+		case r.URL.Path == "/recipe_data/_forcemerge":
+			if r.URL.Query().Get("max_num_segments") != "1" {
+				t.Errorf("unexpected params %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"_shards":{"total":2,"successful":1,"failed":1,"failures":[
+				{"index":"recipe_data_v2","shard":0,"status":"INTERNAL_SERVER_ERROR","reason":{"type":"exception","reason":"merge failed"}}]}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/recipe_data/_settings":
+			w.Write([]byte(`{"recipe_data_v2":{"settings":{"index.number_of_shards":"2","index.number_of_replicas":"0","index.refresh_interval":"-1"}}}`))
+		case r.Method == http.MethodPut && r.URL.Path == "/recipe_data/_settings":
+			b, _ := io.ReadAll(r.Body)
+			settings = string(b)
+			w.Write([]byte(`{"acknowledged":true}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	if ack, err := s.DeleteIndex("recipe_data_v1"); err != nil || !ack.Acknowledged {
+		t.Errorf("DeleteIndex = %+v, %v", ack, err)
+	}
+	if _, err := s.DeleteIndex("missing"); !errors.Is(err, ErrIndexNotFound) {
+		t.Errorf("DeleteIndex(missing): %v", err)
+	}
+	if _, err := s.DeleteIndex(""); err != ErrNoIndexName {
+		t.Errorf("DeleteIndex(\"\"): %v", err)
+	}
+	if ack, err := s.CloseIndex("recipe_data"); err != nil || !ack.ShardsAcknowledged {
+		t.Errorf("CloseIndex = %+v, %v", ack, err)
+	}
+	if _, err := s.OpenIndex("recipe_data"); err != nil {
+		t.Errorf("OpenIndex: %v", err)
+	}
+	if sh, err := s.Refresh("recipe_data"); err != nil || sh.Successful != 2 {
+		t.Errorf("Refresh = %+v, %v", sh, err)
+	}
+	if _, err := s.ClearCache("recipe_data"); err != nil {
+		t.Errorf("ClearCache: %v", err)
+	}
+
+	sh, err := s.ForceMerge("recipe_data", 1)
+	var sfe *ShardFailureError
+	if !errors.As(err, &sfe) || sh.Failed != 1 {
+		t.Fatalf("ForceMerge = %+v, %v", sh, err)
+	}
+	if err.Error() != "force merge: 1 of 2 shards failed: [recipe_data_v2][0] exception: merge failed" {
+		t.Errorf("unexpected message %q", err)
+	}
+
+	list, err := s.GetSettings("recipe_data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Index != "recipe_data_v2" || list[0].Shards != 2 || list[0].Replicas != 0 || list[0].RefreshInterval != "-1" {
+		t.Errorf("unexpected settings %+v", list)
+	}
+
+	if _, err := s.UpdateSettings("recipe_data", WithReplicas(1), WithRefreshInterval(time.Second), WithShards(3)); err != nil {
+		t.Fatal(err)
+	}
+	if settings != `{"index":{"number_of_replicas":1,"refresh_interval":"1s"}}` {
+		t.Errorf("unexpected settings body %s", settings)
+	}
+
+	settings = ""
+	for _, opts := range [][]IndexOption{nil, {WithShards(3)}} {
+		if _, err := s.UpdateSettings("recipe_data", opts...); err != ErrNoSettings {
+			t.Errorf("UpdateSettings(%d options): %v", len(opts), err)
+		}
+	}
+	if settings != "" {
+		t.Errorf("settings sent without any to change: %s", settings)
+	}
+}
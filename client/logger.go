@@ -0,0 +1,240 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/go-logr/logr"
+)
+
+// Level is the severity of a log record.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+	return fmt.Sprintf("LEVEL(%d)", int(l))
+}
+
+// Logger receives the log records of a SearchEngine. kv holds alternating
+// keys and values. Request and response bodies are only logged at
+// LevelDebug, under the key "body".
+type Logger interface {
+	Enabled(level Level) bool
+	Log(ctx context.Context, level Level, msg string, kv ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Enabled(Level) bool                                 { return false }
+func (nopLogger) Log(context.Context, Level, string, ...interface{}) {}
+
+type stdLogger struct {
+	l   *log.Logger
+	min Level
+}
+
+// NewStdLogger writes records at min level and above to l as
+// "LEVEL msg key=value ...".
+func NewStdLogger(l *log.Logger, min Level) Logger {
+	return &stdLogger{l: l, min: min}
+}
+
+func (s *stdLogger) Enabled(level Level) bool { return level >= s.min }
+
+func (s *stdLogger) Log(_ context.Context, level Level, msg string, kv ...interface{}) {
+	if !s.Enabled(level) {
+		return
+	}
+	var b strings.Builder
+	b.WriteString(level.String())
+	b.WriteByte(' ')
+	b.WriteString(msg)
+	for i := 0; i+1 < len(kv); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", kv[i], kv[i+1])
+	}
+	s.l.Print(b.String())
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// NewSlogLogger forwards records to l at the matching slog level.
+func NewSlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l: l}
+}
+
+func slogLevel(level Level) slog.Level {
+	switch level {
+	case LevelDebug:
+		return slog.LevelDebug
+	case LevelWarn:
+		return slog.LevelWarn
+	case LevelError:
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+func (s slogLogger) Enabled(level Level) bool {
+	return s.l.Enabled(context.Background(), slogLevel(level))
+}
+
+func (s slogLogger) Log(ctx context.Context, level Level, msg string, kv ...interface{}) {
+	s.l.Log(ctx, slogLevel(level), msg, kv...)
+}
+
+type logrLogger struct {
+	l logr.Logger
+}
+
+// NewLogrLogger forwards records to l. Debug records are written at
+// verbosity 1, warnings at verbosity 0 with a "level" key.
+func NewLogrLogger(l logr.Logger) Logger {
+	return logrLogger{l: l}
+}
+
+func (g logrLogger) Enabled(level Level) bool {
+	if level == LevelDebug {
+		return g.l.V(1).Enabled()
+	}
+	return g.l.Enabled()
+}
+
+func (g logrLogger) Log(_ context.Context, level Level, msg string, kv ...interface{}) {
+	switch level {
+	case LevelDebug:
+		g.l.V(1).Info(msg, kv...)
+	case LevelWarn:
+		g.l.Info(msg, append([]interface{}{"level", "warn"}, kv...)...)
+	case LevelError:
+		g.l.Error(nil, msg, kv...)
+	default:
+		g.l.Info(msg, kv...)
+	}
+}
+
+// Redactor rewrites a value before it is logged. key is the log key; bodies
+// are passed as their raw JSON string under "body".
+type Redactor func(key string, value interface{}) interface{}
+
+// RedactFields masks the named log keys and the JSON fields of the same name
+// at any depth of logged bodies.
+func RedactFields(names ...string) Redactor {
+	set := make(map[string]bool, len(names))
+	for _, n := range names {
+		set[n] = true
+	}
+	return func(key string, value interface{}) interface{} {
+		if set[key] {
+			return "[REDACTED]"
+		}
+		if key != "body" {
+			return value
+		}
+		s, ok := value.(string)
+		if !ok {
+			return value
+		}
+		return redactJSON(s, set)
+	}
+}
+
+func redactJSON(s string, fields map[string]bool) string {
+	// bulk bodies are newline delimited
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var v interface{}
+		if err := json.Unmarshal([]byte(line), &v); err != nil {
+			return "[REDACTED]"
+		}
+		b, _ := json.Marshal(redactValue(v, fields))
+		lines[i] = string(b)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func redactValue(v interface{}, fields map[string]bool) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			if fields[k] {
+				t[k] = "[REDACTED]"
+			} else {
+				t[k] = redactValue(e, fields)
+			}
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = redactValue(e, fields)
+		}
+	}
+	return v
+}
+
+func (r *SearchEngine) logger() Logger {
+	if r == nil || r.log == nil {
+		return nopLogger{}
+	}
+	return r.log
+}
+
+func (r *SearchEngine) logf(ctx context.Context, level Level, msg string, kv ...interface{}) {
+	l := r.logger()
+	if !l.Enabled(level) {
+		return
+	}
+	if r.redact != nil {
+		for i := 0; i+1 < len(kv); i += 2 {
+			if k, ok := kv[i].(string); ok {
+				kv[i+1] = r.redact(k, kv[i+1])
+			}
+		}
+	}
+	l.Log(ctx, level, msg, kv...)
+}
+
+// logRequest logs a request body at debug level.
+func (r *SearchEngine) logRequest(ctx context.Context, op, indexName, body string) {
+	r.logf(ctx, LevelDebug, op+" request", "index", indexName, "body", body)
+}
+
+// logResponse logs a response at debug level, leaving res.Body readable.
+func (r *SearchEngine) logResponse(ctx context.Context, op string, res *esapi.Response) {
+	if !r.logger().Enabled(LevelDebug) || res.Body == nil {
+		return
+	}
+	b, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	res.Body = io.NopCloser(bytes.NewReader(b))
+	if err != nil {
+		return
+	}
+	r.logf(ctx, LevelDebug, op+" response", "status", res.StatusCode, "body", string(b))
+}
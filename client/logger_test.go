@@ -0,0 +1,192 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type logRecord struct {
+	level Level
+	msg   string
+	kv    []interface{}
+}
+
+// recordLogger keeps the records at min level and above.
+type recordLogger struct {
+	min     Level
+	mu      sync.Mutex
+	records []logRecord
+}
+
+func (l *recordLogger) Enabled(level Level) bool { return level >= l.min }
+
+func (l *recordLogger) Log(_ context.Context, level Level, msg string, kv ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, logRecord{level, msg, kv})
+}
+
+// bodies returns the logged "body" values.
+func (l *recordLogger) bodies() []string {
+	var out []string
+	for _, rec := range l.records {
+		for i := 0; i+1 < len(rec.kv); i += 2 {
+			if rec.kv[i] == "body" {
+				out = append(out, rec.kv[i+1].(string))
+			}
+		}
+	}
+	return out
+}
+
+// logTestHandler answers bulk requests and gets of recipe_data/1.
+func logTestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/_bulk" {
+		w.Write([]byte(`{"took":1,"errors":false,"items":[
+			{"index":{"_index":"recipe_data","_id":"1","result":"created","status":201}}
+		]}`))
+		return
+	}
+	w.Write([]byte(`{"_index":"recipe_data","_id":"1","found":true,"_source":{"title":"apple pie"}}`))
+}
+
+// logSomeCalls makes a bulk request, logging its body, and a get, logging
+// its response.
+func logSomeCalls(t *testing.T, s *SearchEngine) {
+	t.Helper()
+	if _, err := s.BulkCreate("recipe_data", []SearchEngine_Doc{testDoc{ID: "1", Title: "apple pie"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetOne("recipe_data", "1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDefaultEngineIsSilent(t *testing.T) {
+	s := newTestEngine(t, logTestHandler)
+
+	stdout, stderr := os.Stdout, os.Stderr
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var std bytes.Buffer
+	func() {
+		os.Stdout, os.Stderr = pw, pw
+		log.SetOutput(&std)
+		defer func() {
+			os.Stdout, os.Stderr = stdout, stderr
+			log.SetOutput(os.Stderr)
+			pw.Close()
+		}()
+		logSomeCalls(t, s)
+	}()
+
+	out, _ := io.ReadAll(pr)
+	if len(out) != 0 || std.Len() != 0 {
+		t.Errorf("default engine wrote %q %q", out, std.String())
+	}
+}
+
+func TestBodiesOnlyAtDebug(t *testing.T) {
+	info := &recordLogger{min: LevelInfo}
+	s := newTestEngine(t, logTestHandler, WithLogger(info))
+	logSomeCalls(t, s)
+	if b := info.bodies(); len(b) != 0 {
+		t.Errorf("bodies logged at info level: %q", b)
+	}
+
+	debug := &recordLogger{min: LevelDebug}
+	s = newTestEngine(t, logTestHandler, WithLogger(debug))
+	logSomeCalls(t, s)
+	b := debug.bodies()
+	if len(b) != 2 || !strings.Contains(b[0], `"title":"apple pie"`) || !strings.Contains(b[1], `"found":true`) {
+		t.Errorf("expected request and response bodies, got %q", b)
+	}
+	for _, rec := range debug.records {
+		if rec.level != LevelDebug {
+			t.Errorf("unexpected %v record %q", rec.level, rec.msg)
+		}
+	}
+}
+
+func TestRedactFields(t *testing.T) {
+	redact := RedactFields("email", "token")
+	tests := []struct {
+		name  string
+		key   string
+		value interface{}
+		want  interface{}
+	}{
+		{"log key", "token", "secret", "[REDACTED]"},
+		{"other key", "index", "recipe_data", "recipe_data"},
+		{"nested", "body",
+			`{"user":{"email":"a@example.com","name":"ann"},"list":[{"email":"b@example.com"}]}`,
+			`{"list":[{"email":"[REDACTED]"}],"user":{"email":"[REDACTED]","name":"ann"}}`},
+		{"ndjson", "body",
+			"{\"index\":{\"_id\":\"1\"}}\n{\"email\":\"a@example.com\"}\n",
+			"{\"index\":{\"_id\":\"1\"}}\n{\"email\":\"[REDACTED]\"}\n"},
+		{"not json", "body", `email=a@example.com`, "[REDACTED]"},
+		{"ndjson with a bad line", "body", "{\"email\":\"a\"}\nemail=b\n", "[REDACTED]"},
+		{"non string body", "body", 42, 42},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redact(tt.key, tt.value); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactorAppliesToLoggedBodies(t *testing.T) {
+	l := &recordLogger{min: LevelDebug}
+	s := newTestEngine(t, logTestHandler, WithLogger(l), WithRedactor(RedactFields("title")))
+	logSomeCalls(t, s)
+	b := l.bodies()
+	if len(b) != 2 || strings.Contains(strings.Join(b, "\n"), "apple pie") || !strings.Contains(b[0], `"title":"[REDACTED]"`) {
+		t.Errorf("title not redacted: %q", b)
+	}
+}
+
+// chanDoc cannot be encoded to JSON.
+type chanDoc struct {
+	testDoc
+	C chan int `json:"c"`
+}
+
+func TestUpdateEncodeErrorsAreReturned(t *testing.T) {
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	})
+
+	stdout := os.Stdout
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bulkErr, updateErr error
+	func() {
+		os.Stdout = pw
+		defer func() {
+			os.Stdout = stdout
+			pw.Close()
+		}()
+		bulkErr = s.BulkUpdate("recipe_data", []SearchEngine_Doc{chanDoc{testDoc: testDoc{ID: "1"}}})
+		updateErr = s.UpdateDoc("recipe_data", "1", map[string]interface{}{"c": make(chan int)})
+	}()
+
+	if bulkErr == nil || updateErr == nil {
+		t.Errorf("encode errors not returned: %v, %v", bulkErr, updateErr)
+	}
+	if out, _ := io.ReadAll(pr); len(out) != 0 {
+		t.Errorf("wrote %q to stdout", out)
+	}
+}
@@ -0,0 +1,253 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mapping is the mapping of an index: the type and indexing options of each
+// field.
+type Mapping struct {
+	Dynamic    string               `json:"dynamic,omitempty"` // "true", "false", "strict" or "runtime"
+	Properties map[string]*Property `json:"properties"`
+}
+
+// Property is the mapping of one field. Properties holds the sub-fields of
+// object and nested fields, Fields the multi-fields indexed from the same
+// value.
+type Property struct {
+	Type           string               `json:"type,omitempty"`
+	Analyzer       string               `json:"analyzer,omitempty"`
+	SearchAnalyzer string               `json:"search_analyzer,omitempty"`
+	Normalizer     string               `json:"normalizer,omitempty"`
+	Format         string               `json:"format,omitempty"`
+	Index          *bool                `json:"index,omitempty"`
+	Store          *bool                `json:"store,omitempty"`
+	DocValues      *bool                `json:"doc_values,omitempty"`
+	IgnoreAbove    int                  `json:"ignore_above,omitempty"`
+	Properties     map[string]*Property `json:"properties,omitempty"`
+	Fields         map[string]*Property `json:"fields,omitempty"`
+}
+
+// MappingOf derives a mapping from the fields of the struct doc, named as
+// encoding/json names them. The es struct tag sets a field's mapping:
+//
+//	Title  string   `json:"title" es:"type=text,analyzer=english"`
+//	Meal   string   `json:"meal" es:"keyword"`
+//	Steps  []Step   `json:"steps" es:"nested"`
+//	Secret string   `json:"secret" es:"-"`
+//	Img    string   `json:"img" es:"keyword,index=false"`
+//	Name   string   `json:"name" es:"text,fields.raw=keyword"`
+//
+// A bare word is the type. The keys are type, analyzer, search_analyzer,
+// normalizer, format, index, store, doc_values and ignore_above; a key
+// fields.<name> adds a multi-field of that type, such as name.raw above.
+// Fields without a type take one from their Go type: strings are text,
+// time.Time is date, structs are objects and slices map as their elements.
+// Interface fields are left to dynamic mapping.
+func MappingOf(doc interface{}) (*Mapping, error) {
+	t := reflect.TypeOf(doc)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("mapping of %T: not a struct", doc)
+	}
+	props, err := structProperties(t, map[reflect.Type]bool{})
+	if err != nil {
+		return nil, err
+	}
+	return &Mapping{Properties: props}, nil
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	rawJSONType = reflect.TypeOf(json.RawMessage{})
+)
+
+// structProperties maps the fields of t. seen holds the struct types being
+// mapped, as a mapping cannot recurse.
+func structProperties(t reflect.Type, seen map[reflect.Type]bool) (map[string]*Property, error) {
+	if seen[t] {
+		return nil, fmt.Errorf("mapping of %s: recursive type", t)
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	props := map[string]*Property{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("es")
+		if tag == "-" || !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		// embedded structs are flattened, as encoding/json does
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct && tag == "" {
+			sub, err := structProperties(ft, seen)
+			if err != nil {
+				return nil, err
+			}
+			for k, p := range sub {
+				if _, ok := props[k]; !ok {
+					props[k] = p
+				}
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		p, err := fieldProperty(f.Type, tag, seen)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		if p != nil {
+			props[name] = p
+		}
+	}
+	return props, nil
+}
+
+// fieldProperty maps a field of type t with the es tag tag. It returns nil
+// for fields left to dynamic mapping.
+func fieldProperty(t reflect.Type, tag string, seen map[reflect.Type]bool) (*Property, error) {
+	p, err := parseTag(tag)
+	if err != nil {
+		return nil, err
+	}
+	// arrays need no mapping of their own; byte slices are binary
+	for k := t.Kind(); k == reflect.Pointer || (k == reflect.Slice || k == reflect.Array) && t.Elem().Kind() != reflect.Uint8; k = t.Kind() {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct && t != timeType && (p.Type == "" || p.Type == "object" || p.Type == "nested") {
+		if p.Properties, err = structProperties(t, seen); err != nil {
+			return nil, err
+		}
+		if p.Type == "" {
+			p.Type = "object"
+		}
+		return p, nil
+	}
+	if p.Type == "" {
+		p.Type = goFieldType(t)
+		if p.Type == "" && tag == "" {
+			return nil, nil
+		}
+		if p.Type == "" {
+			return nil, fmt.Errorf("no es type for %s", t)
+		}
+	}
+	return p, nil
+}
+
+// goFieldType is the field type for values of the Go type t, or "" for
+// types left to dynamic mapping.
+func goFieldType(t reflect.Type) string {
+	if t == timeType {
+		return "date"
+	}
+	if t == rawJSONType {
+		return ""
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "text"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int8:
+		return "byte"
+	case reflect.Int16, reflect.Uint8:
+		return "short"
+	case reflect.Int32, reflect.Uint16:
+		return "integer"
+	case reflect.Int, reflect.Int64, reflect.Uint32:
+		return "long"
+	case reflect.Uint, reflect.Uint64:
+		return "unsigned_long"
+	case reflect.Float32:
+		return "float"
+	case reflect.Float64:
+		return "double"
+	case reflect.Slice, reflect.Array:
+		// only byte slices get here; encoding/json writes them as base64
+		return "binary"
+	case reflect.Map:
+		return "object"
+	}
+	return ""
+}
+
+// parseTag reads an es struct tag into a Property.
+func parseTag(tag string) (*Property, error) {
+	p := &Property{}
+	if tag == "" {
+		return p, nil
+	}
+	for _, opt := range strings.Split(tag, ",") {
+		key, val, ok := strings.Cut(strings.TrimSpace(opt), "=")
+		if !ok {
+			if p.Type != "" {
+				return nil, fmt.Errorf("es tag %q: more than one type", tag)
+			}
+			p.Type = key
+			continue
+		}
+		var err error
+		switch key {
+		case "type":
+			p.Type = val
+		case "analyzer":
+			p.Analyzer = val
+		case "search_analyzer":
+			p.SearchAnalyzer = val
+		case "normalizer":
+			p.Normalizer = val
+		case "format":
+			p.Format = val
+		case "index":
+			p.Index, err = parseTagBool(val)
+		case "store":
+			p.Store, err = parseTagBool(val)
+		case "doc_values":
+			p.DocValues, err = parseTagBool(val)
+		case "ignore_above":
+			p.IgnoreAbove, err = strconv.Atoi(val)
+		default:
+			sub, ok := strings.CutPrefix(key, "fields.")
+			if !ok || sub == "" || val == "" {
+				return nil, fmt.Errorf("es tag %q: unknown option %q", tag, opt)
+			}
+			if p.Fields == nil {
+				p.Fields = map[string]*Property{}
+			}
+			p.Fields[sub] = &Property{Type: val}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("es tag %q: %s: %w", tag, key, err)
+		}
+	}
+	return p, nil
+}
+
+func parseTagBool(s string) (*bool, error) {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
@@ -0,0 +1,292 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// ChangeKind tells whether a mapping change can be applied to an existing
+// index.
+type ChangeKind int
+
+const (
+	// AdditiveChange is a new field or multi-field, or a changed
+	// search_analyzer or ignore_above of an existing field. Put-mapping
+	// applies it.
+	AdditiveChange ChangeKind = iota
+	// BreakingChange is a changed type or parameter of an existing field.
+	// It needs a new index and a reindex.
+	BreakingChange
+)
+
+func (k ChangeKind) String() string {
+	if k == BreakingChange {
+		return "breaking"
+	}
+	return "additive"
+}
+
+// MappingChange is one difference between the live mapping of an index and
+// the mapping wanted for it.
+type MappingChange struct {
+	Field  string // dotted path, such as "instructions.steps" or "title.raw"
+	Kind   ChangeKind
+	Live   *Property // nil for new fields
+	Want   *Property
+	Reason string // for changed fields, such as "type long, want keyword"
+}
+
+func (c MappingChange) String() string {
+	if c.Live == nil {
+		return c.Field + ": new field"
+	}
+	return c.Field + ": " + c.Reason
+}
+
+// MappingDiff lists how the live mapping of Index differs from a wanted
+// mapping. Fields only the live mapping has are not changes; they cannot be
+// removed and do no harm.
+type MappingDiff struct {
+	Index   string
+	Changes []MappingChange
+
+	patch map[string]*Property // the additive changes, as a put-mapping body
+}
+
+// Additive returns the changes put-mapping can apply.
+func (d *MappingDiff) Additive() []MappingChange {
+	return d.filter(AdditiveChange)
+}
+
+// Breaking returns the changes that need a reindex.
+func (d *MappingDiff) Breaking() []MappingChange {
+	return d.filter(BreakingChange)
+}
+
+func (d *MappingDiff) filter(k ChangeKind) []MappingChange {
+	var list []MappingChange
+	for _, c := range d.Changes {
+		if c.Kind == k {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+// Err returns a *MappingConflictError listing the breaking changes, or nil
+// if there are none.
+func (d *MappingDiff) Err() error {
+	if b := d.Breaking(); len(b) > 0 {
+		return &MappingConflictError{Index: d.Index, Changes: b}
+	}
+	return nil
+}
+
+// DiffMapping compares the live mapping of indexName with the mapping
+// MappingOf derives from the struct doc.
+func (r *SearchEngine) DiffMapping(indexName string, doc interface{}) (*MappingDiff, error) {
+	return r.DiffMappingCtx(context.Background(), indexName, doc)
+}
+
+// DiffMappingCtx is like DiffMapping but carries ctx to the request.
+func (r *SearchEngine) DiffMappingCtx(ctx context.Context, indexName string, doc interface{}) (*MappingDiff, error) {
+	want, err := MappingOf(doc)
+	if err != nil {
+		return nil, err
+	}
+	live, err := r.GetMappingCtx(ctx, indexName)
+	if err != nil {
+		return nil, err
+	}
+	d := &MappingDiff{Index: indexName, patch: map[string]*Property{}}
+	d.diff("", live.Properties, want.Properties, d.patch)
+	sort.Slice(d.Changes, func(i, j int) bool { return d.Changes[i].Field < d.Changes[j].Field })
+	return d, nil
+}
+
+// diff compares the properties under prefix and adds the additive ones to
+// patch.
+func (d *MappingDiff) diff(prefix string, live, want map[string]*Property, patch map[string]*Property) {
+	for name, w := range want {
+		path := prefix + name
+		l, ok := live[name]
+		if !ok {
+			d.Changes = append(d.Changes, MappingChange{Field: path, Kind: AdditiveChange, Want: w})
+			patch[name] = w
+			continue
+		}
+		if reason := propertyConflict(l, w); reason != "" {
+			d.Changes = append(d.Changes, MappingChange{Field: path, Kind: BreakingChange, Live: l, Want: w, Reason: reason})
+			continue
+		}
+		// put-mapping needs the parameters of a field it adds to or updates
+		p := *l
+		p.Properties, p.Fields = nil, nil
+		update := propertyUpdate(l, w)
+		if update != "" {
+			d.Changes = append(d.Changes, MappingChange{Field: path, Kind: AdditiveChange, Live: l, Want: w, Reason: update})
+			p.SearchAnalyzer, p.IgnoreAbove = w.SearchAnalyzer, w.IgnoreAbove
+		}
+		props, fields := map[string]*Property{}, map[string]*Property{}
+		d.diff(path+".", l.Properties, w.Properties, props)
+		d.diff(path+".", l.Fields, w.Fields, fields)
+		if update == "" && len(props) == 0 && len(fields) == 0 {
+			continue
+		}
+		if len(props) > 0 {
+			p.Properties = props
+		}
+		if len(fields) > 0 {
+			p.Fields = fields
+		}
+		patch[name] = &p
+	}
+}
+
+// propertyConflict describes how the wanted property w differs from the
+// live property l in a way put-mapping cannot apply, or returns "".
+func propertyConflict(l, w *Property) string {
+	var diffs paramDiffs
+	diffs.check("type", propertyType(l), propertyType(w))
+	if len(diffs) > 0 {
+		return diffs[0]
+	}
+	diffs.check("analyzer", l.Analyzer, w.Analyzer)
+	diffs.check("normalizer", l.Normalizer, w.Normalizer)
+	diffs.check("format", l.Format, w.Format)
+	diffs.check("index", boolParam(l.Index, true), boolParam(w.Index, true))
+	diffs.check("store", boolParam(l.Store, false), boolParam(w.Store, false))
+	diffs.check("doc_values", boolParam(l.DocValues, true), boolParam(w.DocValues, true))
+	return strings.Join(diffs, ", ")
+}
+
+// propertyUpdate describes the changed parameters of l that put-mapping
+// updates in place, or returns "".
+func propertyUpdate(l, w *Property) string {
+	var diffs paramDiffs
+	diffs.check("search_analyzer", l.SearchAnalyzer, w.SearchAnalyzer)
+	diffs.check("ignore_above", fmt.Sprint(l.IgnoreAbove), fmt.Sprint(w.IgnoreAbove))
+	return strings.Join(diffs, ", ")
+}
+
+// paramDiffs collects "param live, want want" descriptions.
+type paramDiffs []string
+
+func (d *paramDiffs) check(param, live, want string) {
+	if live != want {
+		*d = append(*d, fmt.Sprintf("%s %s, want %s", param, orDefault(live), orDefault(want)))
+	}
+}
+
+// propertyType is the type of p; the live mapping leaves it out for objects.
+func propertyType(p *Property) string {
+	if p.Type == "" && p.Properties != nil {
+		return "object"
+	}
+	return p.Type
+}
+
+func boolParam(b *bool, def bool) string {
+	if b == nil {
+		return fmt.Sprint(def)
+	}
+	return fmt.Sprint(*b)
+}
+
+func orDefault(s string) string {
+	if s == "" || s == "0" {
+		return "default"
+	}
+	return s
+}
+
+// MigrateMapping puts the additive changes between the live mapping of
+// indexName and the mapping of doc. If there are breaking changes it applies
+// nothing and returns the diff with a *MappingConflictError.
+func (r *SearchEngine) MigrateMapping(indexName string, doc interface{}) (*MappingDiff, error) {
+	return r.MigrateMappingCtx(context.Background(), indexName, doc)
+}
+
+// MigrateMappingCtx is like MigrateMapping but carries ctx to the request.
+func (r *SearchEngine) MigrateMappingCtx(ctx context.Context, indexName string, doc interface{}) (*MappingDiff, error) {
+	d, err := r.DiffMappingCtx(ctx, indexName, doc)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.Err(); err != nil {
+		return d, err
+	}
+	if len(d.patch) == 0 {
+		return d, nil
+	}
+	return d, r.PutMappingCtx(ctx, indexName, &Mapping{Properties: d.patch})
+}
+
+// GetMapping returns the live mapping of indexName, which may be an alias
+// of a single index.
+func (r *SearchEngine) GetMapping(indexName string) (*Mapping, error) {
+	return r.GetMappingCtx(context.Background(), indexName)
+}
+
+// GetMappingCtx is like GetMapping but carries ctx to the request.
+func (r *SearchEngine) GetMappingCtx(ctx context.Context, indexName string) (*Mapping, error) {
+	req := esapi.IndicesGetMappingRequest{Index: []string{indexName}}
+	res, err := req.Do(ctx, r.Client())
+	if err != nil {
+		return nil, fmt.Errorf("get mapping request: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, newESError("get mapping", res)
+	}
+	r.logResponse(ctx, "get mapping", res)
+
+	var body map[string]struct {
+		Mappings Mapping `json:"mappings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("get mapping decode: %w", err)
+	}
+	if len(body) != 1 {
+		return nil, fmt.Errorf("get mapping: %s resolves to %d indices", indexName, len(body))
+	}
+	for _, v := range body {
+		m := v.Mappings
+		return &m, nil
+	}
+	return nil, nil
+}
+
+// PutMapping adds the fields of m to the mapping of indexName.
+func (r *SearchEngine) PutMapping(indexName string, m *Mapping) error {
+	return r.PutMappingCtx(context.Background(), indexName, m)
+}
+
+// PutMappingCtx is like PutMapping but carries ctx to the request.
+func (r *SearchEngine) PutMappingCtx(ctx context.Context, indexName string, m *Mapping) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("encode mapping: %w", err)
+	}
+	r.logRequest(ctx, "put mapping", indexName, string(b))
+
+	req := esapi.IndicesPutMappingRequest{
+		Index: []string{indexName},
+		Body:  strings.NewReader(string(b)),
+	}
+	res, err := req.Do(ctx, r.Client())
+	if err != nil {
+		return fmt.Errorf("put mapping request: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return newESError("put mapping", res)
+	}
+	r.logResponse(ctx, "put mapping", res)
+	return nil
+}
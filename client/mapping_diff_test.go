@@ -0,0 +1,121 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestMigrateMapping(t *testing.T) {
+	live := `{"recipe_data_v1":{"mappings":{"properties":{
+		"title":{"type":"text","analyzer":"english"},
+		"user_id":{"type":"keyword"},
+		"instructions":{"type":"nested","properties":{"steps":{"type":"text"}}},
+		"legacy":{"type":"keyword"}}}}}`
+	var put string
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/recipe_data/_mapping":
+			w.Write([]byte(live))
+		case r.Method == http.MethodPut && r.URL.Path == "/recipe_data/_mapping":
+			b, _ := io.ReadAll(r.Body)
+			put = string(b)
+			w.Write([]byte(`{"acknowledged":true}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	type step struct {
+		Steps []string `json:"steps"`
+		Tip   string   `json:"tip" es:"keyword"`
+	}
+	type additive struct {
+		Title  string `json:"title" es:"type=text,analyzer=english,fields.raw=keyword"`
+		UserID string `json:"user_id" es:"keyword"`
+		Steps  []step `json:"instructions" es:"nested"`
+		Serves int8   `json:"serves"`
+	}
+	d, err := s.MigrateMapping("recipe_data", additive{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(d.Additive()); n != 3 || d.Changes[0].Field != "instructions.tip" || d.Changes[2].Field != "title.raw" {
+		t.Errorf("unexpected changes %v", d.Changes)
+	}
+	want := `{"properties":{"instructions":{"type":"nested","properties":{"tip":{"type":"keyword"}}},` +
+		`"serves":{"type":"byte"},"title":{"type":"text","analyzer":"english","fields":{"raw":{"type":"keyword"}}}}}`
+	if put != want {
+		t.Errorf("put mapping\n got %s\nwant %s", put, want)
+	}
+
+	type breaking struct {
+		Title  string `json:"title" es:"type=text"`
+		UserID int64  `json:"user_id"`
+		Serves int8   `json:"serves"`
+	}
+	put = ""
+	d, err = s.MigrateMapping("recipe_data", breaking{})
+	if !errors.Is(err, ErrBreakingMapping) || put != "" {
+		t.Fatalf("expected a conflict and no put, got %v, %q", err, put)
+	}
+	if err.Error() != "index recipe_data: 2 breaking mapping changes: title: analyzer english, want default; user_id: type keyword, want long" {
+		t.Errorf("unexpected message %q", err)
+	}
+	if len(d.Additive()) != 1 {
+		t.Errorf("the diff should still list the new field: %v", d.Changes)
+	}
+}
+
+func TestMigrateMappingUpdatesParams(t *testing.T) {
+	live := `{"recipe_data_v1":{"mappings":{"properties":{
+		"title":{"type":"text","analyzer":"english","search_analyzer":"english"},
+		"user_id":{"type":"keyword","ignore_above":256}}}}}`
+	var put string
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/recipe_data/_mapping":
+			w.Write([]byte(live))
+		case r.Method == http.MethodPut && r.URL.Path == "/recipe_data/_mapping":
+			b, _ := io.ReadAll(r.Body)
+			put = string(b)
+			w.Write([]byte(`{"acknowledged":true}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		doc    interface{}
+		change string
+		put    string
+	}{
+		{"search_analyzer", struct {
+			Title  string `json:"title" es:"type=text,analyzer=english,search_analyzer=standard"`
+			UserID string `json:"user_id" es:"type=keyword,ignore_above=256"`
+		}{}, "title: search_analyzer english, want standard",
+			`{"properties":{"title":{"type":"text","analyzer":"english","search_analyzer":"standard"}}}`},
+		{"ignore_above", struct {
+			Title  string `json:"title" es:"type=text,analyzer=english,search_analyzer=english"`
+			UserID string `json:"user_id" es:"type=keyword,ignore_above=512"`
+		}{}, "user_id: ignore_above 256, want 512",
+			`{"properties":{"user_id":{"type":"keyword","ignore_above":512}}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			put = ""
+			d, err := s.MigrateMapping("recipe_data", tt.doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(d.Changes) != 1 || d.Changes[0].Kind != AdditiveChange || d.Changes[0].String() != tt.change {
+				t.Errorf("unexpected changes %v", d.Changes)
+			}
+			if put != tt.put {
+				t.Errorf("put mapping\n got %s\nwant %s", put, tt.put)
+			}
+		})
+	}
+}
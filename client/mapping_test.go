@@ -0,0 +1,96 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type testStep struct {
+	Title string   `json:"group_title" es:"type=text,analyzer=english"`
+	Steps []string `json:"steps"`
+}
+
+type testMeta struct {
+	Created time.Time `json:"created" es:"format=strict_date_optional_time"`
+}
+
+type testRecipe struct {
+	testMeta
+	ID      string          `json:"id" es:"-"`
+	UserID  int32           `json:"user_id" es:"keyword"`
+	Title   string          `json:"title" es:"type=text,analyzer=english,fields.raw=keyword"`
+	Serves  int8            `json:"serves"`
+	Img     string          `json:"img" es:"keyword,index=false"`
+	Steps   []testStep      `json:"instructions" es:"nested"`
+	Author  *testStep       `json:"author"`
+	Labels  []string        `json:"labels" es:"keyword"`
+	Extra   json.RawMessage `json:"extra"`
+	Skipped string          `json:"-"`
+	private string
+}
+
+func TestMappingOf(t *testing.T) {
+	m, err := MappingOf(&testRecipe{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := json.Marshal(m)
+	want := `{"properties":{` +
+		`"author":{"type":"object","properties":{"group_title":{"type":"text","analyzer":"english"},"steps":{"type":"text"}}},` +
+		`"created":{"type":"date","format":"strict_date_optional_time"},` +
+		`"img":{"type":"keyword","index":false},` +
+		`"instructions":{"type":"nested","properties":{"group_title":{"type":"text","analyzer":"english"},"steps":{"type":"text"}}},` +
+		`"labels":{"type":"keyword"},` +
+		`"serves":{"type":"byte"},` +
+		`"title":{"type":"text","analyzer":"english","fields":{"raw":{"type":"keyword"}}},` +
+		`"user_id":{"type":"keyword"}}}`
+	if string(got) != want {
+		t.Errorf("mapping\n got %s\nwant %s", got, want)
+	}
+
+	type loop struct {
+		Next *loop `json:"next"`
+	}
+	if _, err := MappingOf(loop{}); err == nil {
+		t.Error("expected an error for a recursive type")
+	}
+	type typo struct {
+		Title string `json:"title" es:"text,analyser=english"`
+	}
+	if _, err := MappingOf(typo{}); err == nil {
+		t.Error("expected an error for an unknown tag option")
+	}
+}
+
+func TestCreateIndexWithSettings(t *testing.T) {
+	var created string
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodHead:
+			w.WriteHeader(404)
+		case http.MethodPut:
+			b, _ := io.ReadAll(r.Body)
+			created = string(b)
+			w.Write([]byte(`{"acknowledged":true,"shards_acknowledged":true,"index":"recipe_data"}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	type doc struct {
+		Meal string `json:"meal" es:"keyword"`
+	}
+	err := s.Index("recipe_data", WithShards(2), WithReplicas(0), WithMappingOf(doc{}),
+		WithAnalysis(map[string]interface{}{"analyzer": map[string]interface{}{"folded": map[string]interface{}{"tokenizer": "standard"}}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"mappings":{"properties":{"meal":{"type":"keyword"}}},` +
+		`"settings":{"index":{"analysis":{"analyzer":{"folded":{"tokenizer":"standard"}}},"number_of_replicas":0,"number_of_shards":2}}}`
+	if created != want {
+		t.Errorf("create body\n got %s\nwant %s", created, want)
+	}
+}
@@ -0,0 +1,343 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// Option configures a SearchEngine created by NewSearchEngine.
+type Option func(*engineOptions)
+
+type engineOptions struct {
+	config elasticsearch.Config
+	client *elasticsearch.Client
+	tls    *tls.Config
+	log    Logger
+	redact Redactor
+	retry  *RetryPolicy
+	fields []string
+	err    error
+}
+
+func (o *engineOptions) fail(err error) {
+	if o.err == nil {
+		o.err = err
+	}
+}
+
+func (o *engineOptions) tlsConfig() *tls.Config {
+	if o.tls == nil {
+		o.tls = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return o.tls
+}
+
+// esConfig returns the elasticsearch.Config with the retry policy and the TLS
+// options folded into it.
+func (o *engineOptions) esConfig() (elasticsearch.Config, error) {
+	cfg := o.config
+	if p := o.retry; p != nil {
+		cfg.RetryOnStatus = p.RetryOnStatus
+		cfg.MaxRetries = p.MaxAttempts - 1
+		cfg.RetryBackoff = p.backoff
+		// the transport reads MaxRetries 0 as its default of 3
+		cfg.DisableRetry = p.MaxAttempts == 1
+	}
+	if o.tls == nil {
+		return cfg, nil
+	}
+	var tr *http.Transport
+	switch t := cfg.Transport.(type) {
+	case nil:
+		tr = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		tr = t.Clone()
+	default:
+		return cfg, fmt.Errorf("TLS options need an *http.Transport, got %T", cfg.Transport)
+	}
+	tr.TLSClientConfig = o.tls
+	cfg.Transport = tr
+	return cfg, nil
+}
+
+// WithAddresses sets the cluster nodes to connect to.
+func WithAddresses(addrs ...string) Option {
+	return func(o *engineOptions) {
+		o.config.Addresses = append(o.config.Addresses, addrs...)
+	}
+}
+
+// WithConfig starts from a complete elasticsearch.Config. Options applied
+// after it still take effect.
+func WithConfig(cfg elasticsearch.Config) Option {
+	return func(o *engineOptions) {
+		o.config = cfg
+	}
+}
+
+// WithTransport sets the HTTP transport used to reach the cluster.
+func WithTransport(t http.RoundTripper) Option {
+	return func(o *engineOptions) {
+		o.config.Transport = t
+	}
+}
+
+// WithClient makes the SearchEngine use an already built client. Connection
+// options are ignored when it is set.
+func WithClient(c *elasticsearch.Client) Option {
+	return func(o *engineOptions) {
+		o.client = c
+	}
+}
+
+// WithLogger sends the engine's log records to l. Without it the engine is
+// silent.
+func WithLogger(l Logger) Option {
+	return func(o *engineOptions) {
+		o.log = l
+	}
+}
+
+// WithRedactor rewrites logged values, for example RedactFields("email").
+func WithRedactor(fn Redactor) Option {
+	return func(o *engineOptions) {
+		o.redact = fn
+	}
+}
+
+// WithCloudID connects to an Elastic Cloud deployment instead of Addresses.
+func WithCloudID(id string) Option {
+	return func(o *engineOptions) {
+		o.config.CloudID = id
+	}
+}
+
+// WithBasicAuth authenticates with a username and password.
+func WithBasicAuth(username string, password Secret) Option {
+	return func(o *engineOptions) {
+		p, err := password.resolve()
+		if err != nil {
+			o.fail(fmt.Errorf("password: %w", err))
+			return
+		}
+		o.config.Username = username
+		o.config.Password = p
+	}
+}
+
+// WithAPIKey authenticates with a base64 encoded API key. It takes precedence
+// over basic auth and service tokens.
+func WithAPIKey(key Secret) Option {
+	return func(o *engineOptions) {
+		k, err := key.resolve()
+		if err != nil {
+			o.fail(fmt.Errorf("api key: %w", err))
+			return
+		}
+		o.config.APIKey = k
+	}
+}
+
+// WithServiceToken authenticates with a service account token. It takes
+// precedence over basic auth.
+func WithServiceToken(token Secret) Option {
+	return func(o *engineOptions) {
+		t, err := token.resolve()
+		if err != nil {
+			o.fail(fmt.Errorf("service token: %w", err))
+			return
+		}
+		o.config.ServiceToken = t
+	}
+}
+
+// WithCACert trusts the PEM encoded CA certificates instead of the system
+// pool.
+func WithCACert(pem []byte) Option {
+	return func(o *engineOptions) {
+		t := o.tlsConfig()
+		if t.RootCAs == nil {
+			t.RootCAs = x509.NewCertPool()
+		}
+		if !t.RootCAs.AppendCertsFromPEM(pem) {
+			o.fail(fmt.Errorf("no certificates found in CA bundle"))
+		}
+	}
+}
+
+// WithCACertFile is WithCACert reading the bundle from path.
+func WithCACertFile(path string) Option {
+	return func(o *engineOptions) {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			o.fail(fmt.Errorf("read CA bundle: %w", err))
+			return
+		}
+		WithCACert(b)(o)
+	}
+}
+
+// WithClientCert presents the PEM encoded certificate and key files for
+// mutual TLS.
+func WithClientCert(certFile, keyFile string) Option {
+	return func(o *engineOptions) {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			o.fail(fmt.Errorf("load client certificate: %w", err))
+			return
+		}
+		t := o.tlsConfig()
+		t.Certificates = append(t.Certificates, cert)
+	}
+}
+
+// WithCertificateFingerprint pins the server certificate by its SHA256 hex
+// fingerprint, as printed by Elasticsearch on first start.
+func WithCertificateFingerprint(fingerprint string) Option {
+	return func(o *engineOptions) {
+		o.config.CertificateFingerprint = fingerprint
+	}
+}
+
+// WithInsecureSkipVerify disables server certificate checks. Only use it
+// against local development clusters.
+func WithInsecureSkipVerify() Option {
+	return func(o *engineOptions) {
+		o.tlsConfig().InsecureSkipVerify = true
+	}
+}
+
+// Secret is a credential given inline, through an environment variable or
+// through a file. The first non-empty source wins, in that order.
+type Secret struct {
+	Value string
+	Env   string
+	File  string
+}
+
+// SecretValue is a Secret holding v itself.
+func SecretValue(v string) Secret { return Secret{Value: v} }
+
+// SecretEnv is a Secret read from the environment variable name.
+func SecretEnv(name string) Secret { return Secret{Env: name} }
+
+// SecretFile is a Secret read from the file at path, without the trailing
+// newline.
+func SecretFile(path string) Secret { return Secret{File: path} }
+
+func (s Secret) resolve() (string, error) {
+	switch {
+	case s.Value != "":
+		return s.Value, nil
+	case s.Env != "":
+		v, ok := os.LookupEnv(s.Env)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", s.Env)
+		}
+		return v, nil
+	case s.File != "":
+		b, err := os.ReadFile(s.File)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	}
+	return "", nil
+}
+
+// Config gathers the connection settings that are usually kept in
+// application configuration. Empty fields are left alone.
+type Config struct {
+	Addresses []string
+	CloudID   string
+
+	Username     string
+	Password     Secret
+	APIKey       Secret
+	ServiceToken Secret
+
+	CACertFile             string
+	CertFile               string
+	KeyFile                string
+	CertificateFingerprint string
+	InsecureSkipVerify     bool
+}
+
+// WithSettings applies every non-empty field of cfg.
+func WithSettings(cfg Config) Option {
+	return func(o *engineOptions) {
+		var opts []Option
+		if len(cfg.Addresses) > 0 {
+			opts = append(opts, WithAddresses(cfg.Addresses...))
+		}
+		if cfg.CloudID != "" {
+			opts = append(opts, WithCloudID(cfg.CloudID))
+		}
+		if cfg.Username != "" {
+			opts = append(opts, WithBasicAuth(cfg.Username, cfg.Password))
+		}
+		if cfg.APIKey != (Secret{}) {
+			opts = append(opts, WithAPIKey(cfg.APIKey))
+		}
+		if cfg.ServiceToken != (Secret{}) {
+			opts = append(opts, WithServiceToken(cfg.ServiceToken))
+		}
+		if cfg.CACertFile != "" {
+			opts = append(opts, WithCACertFile(cfg.CACertFile))
+		}
+		if cfg.CertFile != "" || cfg.KeyFile != "" {
+			opts = append(opts, WithClientCert(cfg.CertFile, cfg.KeyFile))
+		}
+		if cfg.CertificateFingerprint != "" {
+			opts = append(opts, WithCertificateFingerprint(cfg.CertificateFingerprint))
+		}
+		if cfg.InsecureSkipVerify {
+			opts = append(opts, WithInsecureSkipVerify())
+		}
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
+
+// ConfigFromEnv reads a Config from environment variables named
+// prefix + ADDRESSES (comma separated), CLOUD_ID, USERNAME, PASSWORD, API_KEY,
+// SERVICE_TOKEN, CA_CERT, CERT, KEY and CERT_FINGERPRINT. Each secret may
+// also be given as a file through the same name with a _FILE suffix.
+func ConfigFromEnv(prefix string) Config {
+	get := func(name string) string { return os.Getenv(prefix + name) }
+	secret := func(name string) Secret {
+		if _, ok := os.LookupEnv(prefix + name); ok {
+			return SecretEnv(prefix + name)
+		}
+		if f := get(name + "_FILE"); f != "" {
+			return SecretFile(f)
+		}
+		return Secret{}
+	}
+	cfg := Config{
+		CloudID:                get("CLOUD_ID"),
+		Username:               get("USERNAME"),
+		Password:               secret("PASSWORD"),
+		APIKey:                 secret("API_KEY"),
+		ServiceToken:           secret("SERVICE_TOKEN"),
+		CACertFile:             get("CA_CERT"),
+		CertFile:               get("CERT"),
+		KeyFile:                get("KEY"),
+		CertificateFingerprint: get("CERT_FINGERPRINT"),
+	}
+	if a := get("ADDRESSES"); a != "" {
+		for _, addr := range strings.Split(a, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				cfg.Addresses = append(cfg.Addresses, addr)
+			}
+		}
+	}
+	return cfg
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"encoding/pem"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTLSServer(t *testing.T, check func(r *http.Request) bool) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		if !check(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"type":"security_exception","reason":"unauthorized"},"status":401}`))
+			return
+		}
+		w.Write([]byte(`{"_index":"recipe_data","_id":"1","found":true,"_source":{"title":"apple pie"}}`))
+	}))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func caPEM(srv *httptest.Server) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+}
+
+func TestBasicAuthOverTLS(t *testing.T) {
+	srv := newTLSServer(t, func(r *http.Request) bool {
+		u, p, ok := r.BasicAuth()
+		return ok && u == "elastic" && p == "changeme"
+	})
+
+	dir := t.TempDir()
+	pwFile := filepath.Join(dir, "password")
+	if err := os.WriteFile(pwFile, []byte("changeme\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(caFile, caPEM(srv), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewSearchEngine(WithSettings(Config{
+		Addresses:  []string{srv.URL},
+		Username:   "elastic",
+		Password:   SecretFile(pwFile),
+		CACertFile: caFile,
+	}))
+	if err != nil {
+		t.Fatalf("NewSearchEngine: %v", err)
+	}
+	hit, err := s.GetOne("recipe_data", "1")
+	if err != nil {
+		t.Fatalf("GetOne: %v", err)
+	}
+	if hit.ID != "1" {
+		t.Errorf("got id %q", hit.ID)
+	}
+}
+
+func TestAPIKeyFromEnv(t *testing.T) {
+	srv := newTLSServer(t, func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "APIKey c2VjcmV0"
+	})
+	t.Setenv("TEST_ES_API_KEY", "c2VjcmV0")
+
+	s, err := NewSearchEngine(WithAddresses(srv.URL), WithAPIKey(SecretEnv("TEST_ES_API_KEY")), WithCACert(caPEM(srv)))
+	if err != nil {
+		t.Fatalf("NewSearchEngine: %v", err)
+	}
+	if _, err := s.GetOne("recipe_data", "1"); err != nil {
+		t.Fatalf("GetOne: %v", err)
+	}
+}
+
+func TestUntrustedServer(t *testing.T) {
+	srv := newTLSServer(t, func(*http.Request) bool { return true })
+
+	s, err := NewSearchEngine(WithAddresses(srv.URL), WithBasicAuth("elastic", SecretValue("changeme")))
+	if err != nil {
+		t.Fatalf("NewSearchEngine: %v", err)
+	}
+	if _, err := s.GetOne("recipe_data", "1"); err == nil {
+		t.Fatal("expected a certificate error")
+	}
+}
+
+func TestMissingSecret(t *testing.T) {
+	_, err := NewSearchEngine(WithAddresses("https://localhost:9200"), WithAPIKey(SecretEnv("TEST_ES_UNSET_KEY")))
+	if err == nil {
+		t.Fatal("expected an error for an unset environment variable")
+	}
+}
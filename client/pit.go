@@ -0,0 +1,245 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// ErrInvalidCursor is returned by ResumePaginator for tokens it did not
+// issue.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// PaginatorConfig controls the pages of a Paginator. Zero values take the
+// defaults noted on each field.
+type PaginatorConfig struct {
+	Size      int           // hits per page, default 100
+	KeepAlive time.Duration // how long the point-in-time survives between pages, default 1m
+
+	// Sort orders the pages. A _shard_doc tiebreaker is always appended so
+	// that every hit has a unique position.
+	Sort []Sorter
+
+	// Options add to every page, for example WithSource or WithFields.
+	// Paging options such as WithFrom and WithSort are ignored.
+	Options []SearchOption
+}
+
+// Paginator pages through the hits of a query on a point-in-time view of an
+// index, using search_after. Pages stay stable while documents change.
+//
+// A web handler hands Cursor to its client and picks up the next request
+// with ResumePaginator. Once the last page was read the point-in-time is
+// closed; call Close to give it up earlier.
+type Paginator struct {
+	engine *SearchEngine
+	state  cursorState
+	done   bool
+}
+
+// cursorState is everything needed to fetch the next page. Only the
+// point-in-time and the position go into the cursor token: the client holds
+// it, so the query and options come from the caller again on resume.
+type cursorState struct {
+	PitID     string        `json:"pit"`
+	KeepAlive string        `json:"keep_alive"`
+	Size      int           `json:"size"`
+	After     []interface{} `json:"after,omitempty"`
+
+	Query json.RawMessage            `json:"-"`
+	Sort  []interface{}              `json:"-"`
+	Extra map[string]json.RawMessage `json:"-"` // body options
+}
+
+// newCursorState builds the state of the first page of q.
+func newCursorState(q Query, cfg PaginatorConfig) (cursorState, error) {
+	if cfg.Size <= 0 {
+		cfg.Size = 100
+	}
+	if cfg.KeepAlive <= 0 {
+		cfg.KeepAlive = time.Minute
+	}
+	if q == nil {
+		q = NewMatchAllQuery()
+	}
+	qb, err := json.Marshal(q.Source())
+	if err != nil {
+		return cursorState{}, fmt.Errorf("encode query: %w", err)
+	}
+	var extra map[string]json.RawMessage
+	for k, v := range newSearchOptions(cfg.Options).body {
+		if k == "sort" || k == "search_after" {
+			continue
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return cursorState{}, fmt.Errorf("encode %s: %w", k, err)
+		}
+		if extra == nil {
+			extra = map[string]json.RawMessage{}
+		}
+		extra[k] = b
+	}
+	return cursorState{
+		KeepAlive: fmt.Sprintf("%dms", cfg.KeepAlive.Milliseconds()),
+		Size:      cfg.Size,
+		Query:     qb,
+		Sort:      append(sortSources(cfg.Sort), map[string]string{"_shard_doc": "asc"}),
+		Extra:     extra,
+	}, nil
+}
+
+// NewPaginator opens a point-in-time on indexName for q.
+func (r *SearchEngine) NewPaginator(indexName string, q Query, cfg PaginatorConfig) (*Paginator, error) {
+	return r.NewPaginatorCtx(context.Background(), indexName, q, cfg)
+}
+
+// NewPaginatorCtx is like NewPaginator but carries ctx to the request.
+func (r *SearchEngine) NewPaginatorCtx(ctx context.Context, indexName string, q Query, cfg PaginatorConfig) (*Paginator, error) {
+	st, err := newCursorState(q, cfg)
+	if err != nil {
+		return nil, err
+	}
+	req := esapi.OpenPointInTimeRequest{
+		Index:     []string{indexName},
+		KeepAlive: st.KeepAlive,
+	}
+	res, err := req.Do(ctx, r.Client())
+	if err != nil {
+		return nil, fmt.Errorf("open pit request: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, newESError("open pit", res)
+	}
+	var body struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("open pit decode: %w", err)
+	}
+	st.PitID = body.ID
+	return &Paginator{engine: r, state: st}, nil
+}
+
+// ResumePaginator continues the pagination a Cursor token was taken from.
+// q and cfg must be those the Paginator was created with; the Size and
+// KeepAlive of cfg are taken from the cursor.
+func (r *SearchEngine) ResumePaginator(cursor string, q Query, cfg PaginatorConfig) (*Paginator, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var tok cursorState
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&tok); err != nil || tok.PitID == "" || tok.Size <= 0 {
+		return nil, ErrInvalidCursor
+	}
+	if d, err := time.ParseDuration(tok.KeepAlive); err != nil || d <= 0 {
+		return nil, ErrInvalidCursor
+	}
+	st, err := newCursorState(q, cfg)
+	if err != nil {
+		return nil, err
+	}
+	// search_after takes one value per sort field
+	if len(tok.After) > 0 && len(tok.After) != len(st.Sort) {
+		return nil, ErrInvalidCursor
+	}
+	for _, v := range tok.After {
+		switch v.(type) {
+		case string, json.Number, bool, nil:
+		default:
+			return nil, ErrInvalidCursor
+		}
+	}
+	st.PitID, st.KeepAlive, st.Size, st.After = tok.PitID, tok.KeepAlive, tok.Size, tok.After
+	return &Paginator{engine: r, state: st}, nil
+}
+
+// Next returns the next page. After the last page Done reports true and
+// Next returns an empty result.
+func (p *Paginator) Next(ctx context.Context) (*SearchResult, error) {
+	if p.done {
+		return &SearchResult{}, nil
+	}
+	body := map[string]interface{}{}
+	for k, v := range p.state.Extra {
+		body[k] = v
+	}
+	body["size"] = p.state.Size
+	body["query"] = p.state.Query
+	body["pit"] = map[string]string{"id": p.state.PitID, "keep_alive": p.state.KeepAlive}
+	body["sort"] = p.state.Sort
+	if len(p.state.After) > 0 {
+		body["search_after"] = p.state.After
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("encode query: %w", err)
+	}
+	// the index comes from the point-in-time
+	req := esapi.SearchRequest{Body: strings.NewReader(string(b))}
+	res, err := p.engine.doSearch(ctx, req, "", string(b))
+	if err != nil {
+		return nil, err
+	}
+	if res.PitID != "" {
+		p.state.PitID = res.PitID
+	}
+	if n := len(res.Hits); n > 0 {
+		p.state.After = res.Hits[n-1].Sort
+	}
+	if len(res.Hits) < p.state.Size {
+		p.done = true
+		if err := p.Close(ctx); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// Done reports whether the last page was read.
+func (p *Paginator) Done() bool {
+	return p.done
+}
+
+// Cursor returns an opaque, URL safe token for the page after the last one
+// read, or "" once done. The token stays usable for KeepAlive.
+func (p *Paginator) Cursor() string {
+	if p.done {
+		return ""
+	}
+	b, _ := json.Marshal(p.state)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Close releases the point-in-time. It is safe to call more than once.
+func (p *Paginator) Close(ctx context.Context) error {
+	if p.state.PitID == "" {
+		return nil
+	}
+	b, _ := json.Marshal(map[string]string{"id": p.state.PitID})
+	p.state.PitID = ""
+	p.done = true
+	req := esapi.ClosePointInTimeRequest{Body: bytes.NewReader(b)}
+	res, err := req.Do(ctx, p.engine.Client())
+	if err != nil {
+		return fmt.Errorf("close pit request: %w", err)
+	}
+	defer res.Body.Close()
+	// an expired point-in-time is already gone
+	if res.IsError() && res.StatusCode != 404 {
+		return newESError("close pit", res)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPaginatorResumesFromCursor(t *testing.T) {
+	var closed string
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/recipe_data/_pit":
+			w.Write([]byte(`{"id":"pit-1"}`))
+		case r.URL.Path == "/_search":
+			b, _ := io.ReadAll(r.Body)
+			var body struct {
+				PIT         struct{ ID string } `json:"pit"`
+				SearchAfter json.RawMessage     `json:"search_after"`
+			}
+			json.Unmarshal(b, &body)
+			if !strings.Contains(string(b), `{"_shard_doc":"asc"}`) {
+				t.Errorf("missing tiebreaker in %s", b)
+			}
+			if !strings.Contains(string(b), `"fields":["title"]`) {
+				t.Errorf("missing fields option in %s", b)
+			}
+			switch string(body.SearchAfter) {
+			case "":
+				w.Write([]byte(`{"pit_id":"pit-2","hits":{"hits":[
+					{"_id":"1","_source":{},"sort":["2024-01-01",9007199254740991]},
+					{"_id":"2","_source":{},"sort":["2024-01-02",9007199254740993]}]}}`))
+			case `["2024-01-02",9007199254740993]`:
+				if body.PIT.ID != "pit-2" {
+					t.Errorf("expected refreshed pit id, got %q", body.PIT.ID)
+				}
+				w.Write([]byte(`{"pit_id":"pit-2","hits":{"hits":[{"_id":"3","_source":{},"sort":["2024-01-03",1]}]}}`))
+			default:
+				t.Errorf("unexpected search_after %s", body.SearchAfter)
+			}
+		case r.Method == http.MethodDelete && r.URL.Path == "/_pit":
+			b, _ := io.ReadAll(r.Body)
+			closed = string(b)
+			w.Write([]byte(`{"succeeded":true,"num_freed":1}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+	ctx := context.Background()
+
+	cfg := PaginatorConfig{
+		Size:    2,
+		Sort:    []Sorter{NewFieldSort("created")},
+		Options: []SearchOption{WithFields("title")},
+	}
+	p, err := s.NewPaginator("recipe_data", nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := p.Next(ctx)
+	if err != nil || len(page.Hits) != 2 || p.Done() {
+		t.Fatalf("first page: %v %+v", err, page)
+	}
+
+	cursor := p.Cursor()
+	if strings.Contains(string(mustDecodeCursor(t, cursor)), "title") {
+		t.Errorf("cursor carries the search options: %s", mustDecodeCursor(t, cursor))
+	}
+	p, err = s.ResumePaginator(cursor, nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err = p.Next(ctx)
+	if err != nil || len(page.Hits) != 1 || page.Hits[0].ID != "3" {
+		t.Fatalf("second page: %v %+v", err, page)
+	}
+	if !p.Done() || p.Cursor() != "" {
+		t.Error("paginator should be done")
+	}
+	if closed != `{"id":"pit-2"}` {
+		t.Errorf("pit not closed, got %q", closed)
+	}
+
+	if _, err := s.ResumePaginator("not a cursor", nil, cfg); err != ErrInvalidCursor {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func mustDecodeCursor(t *testing.T, cursor string) []byte {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestResumePaginatorRejectsTamperedCursor(t *testing.T) {
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	})
+	cfg := PaginatorConfig{Sort: []Sorter{NewFieldSort("created")}}
+	for _, tok := range []string{
+		`{"pit":"p","keep_alive":"60000ms","size":2,"after":["a",1],"query":{"match_all":{}}}`,
+		`{"pit":"p","keep_alive":"60000ms","size":2,"after":["a",1],"extra":{"script_fields":{}}}`,
+		`{"pit":"p","keep_alive":"60000ms","size":2,"after":["a",1],"sort":[{"_script":{}}]}`,
+		`{"pit":"p","keep_alive":"60000ms","size":2,"after":["a",1,2]}`,
+		`{"pit":"p","keep_alive":"60000ms","size":2,"after":[{"script":"x"},1]}`,
+		`{"pit":"p","keep_alive":"forever","size":2}`,
+		`{"pit":"","keep_alive":"60000ms","size":2}`,
+	} {
+		cursor := base64.RawURLEncoding.EncodeToString([]byte(tok))
+		if _, err := s.ResumePaginator(cursor, nil, cfg); err != ErrInvalidCursor {
+			t.Errorf("%s: expected ErrInvalidCursor, got %v", tok, err)
+		}
+	}
+	good := base64.RawURLEncoding.EncodeToString([]byte(`{"pit":"p","keep_alive":"60000ms","size":2,"after":["a",1]}`))
+	if _, err := s.ResumePaginator(good, nil, cfg); err != nil {
+		t.Errorf("valid cursor rejected: %v", err)
+	}
+}
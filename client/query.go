@@ -0,0 +1,476 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Query is a node of the query DSL. Source returns the clause as it appears
+// under "query", e.g. {"term": {"meal": "dinner"}}.
+//
+// Queries are built with the New*Query constructors and combined with
+// NewBoolQuery:
+//
+//	q := NewBoolQuery().
+//		Must(NewMatchQuery("title", "apple pie")).
+//		Filter(NewTermQuery("meal", "dessert"), NewRangeQuery("serves").Gte(4))
+type Query interface {
+	Source() map[string]interface{}
+}
+
+// querySources returns the sources of qs, skipping nil queries.
+func querySources(qs []Query) []interface{} {
+	l := make([]interface{}, 0, len(qs))
+	for _, q := range qs {
+		if q != nil {
+			l = append(l, q.Source())
+		}
+	}
+	return l
+}
+
+// RawQuery is a query clause given as JSON, for DSL features without a
+// builder. A RawQuery that is not a JSON object makes the request using it
+// fail; NewRawQuery reports that up front.
+type RawQuery json.RawMessage
+
+// NewRawQuery checks that src is a JSON object and returns it as a RawQuery.
+func NewRawQuery(src string) (RawQuery, error) {
+	if _, err := rawQuerySource(RawQuery(src)); err != nil {
+		return nil, err
+	}
+	return RawQuery(src), nil
+}
+
+func (q RawQuery) Source() map[string]interface{} {
+	m, err := rawQuerySource(q)
+	if err != nil {
+		return map[string]interface{}{"raw": invalidQuery{err}}
+	}
+	return m
+}
+
+func rawQuerySource(q RawQuery) (map[string]interface{}, error) {
+	if !json.Valid(q) {
+		return nil, fmt.Errorf("invalid raw query %q", string(q))
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(q, &m); err != nil || m == nil {
+		return nil, fmt.Errorf("raw query %q is not a JSON object", string(q))
+	}
+	return m, nil
+}
+
+// invalidQuery stands in for a clause that cannot be sent: encoding a
+// request body containing it fails with err.
+type invalidQuery struct {
+	err error
+}
+
+func (q invalidQuery) MarshalJSON() ([]byte, error) { return nil, q.err }
+
+// BoolQuery combines queries with must, should, filter and must_not.
+type BoolQuery struct {
+	must, should, filter, mustNot []Query
+	minimumShouldMatch            string
+	boost                         *float64
+}
+
+func NewBoolQuery() *BoolQuery { return &BoolQuery{} }
+
+// Must adds clauses that have to match and contribute to the score.
+func (q *BoolQuery) Must(qs ...Query) *BoolQuery {
+	q.must = append(q.must, qs...)
+	return q
+}
+
+// Should adds clauses of which at least minimum_should_match have to match.
+func (q *BoolQuery) Should(qs ...Query) *BoolQuery {
+	q.should = append(q.should, qs...)
+	return q
+}
+
+// Filter adds clauses that have to match without affecting the score.
+func (q *BoolQuery) Filter(qs ...Query) *BoolQuery {
+	q.filter = append(q.filter, qs...)
+	return q
+}
+
+// MustNot adds clauses that must not match.
+func (q *BoolQuery) MustNot(qs ...Query) *BoolQuery {
+	q.mustNot = append(q.mustNot, qs...)
+	return q
+}
+
+// MinimumShouldMatch sets how many should clauses have to match, as a count
+// ("2") or a percentage ("75%").
+func (q *BoolQuery) MinimumShouldMatch(m string) *BoolQuery {
+	q.minimumShouldMatch = m
+	return q
+}
+
+func (q *BoolQuery) Boost(b float64) *BoolQuery {
+	q.boost = &b
+	return q
+}
+
+func (q *BoolQuery) Source() map[string]interface{} {
+	b := map[string]interface{}{}
+	for name, qs := range map[string][]Query{"must": q.must, "should": q.should, "filter": q.filter, "must_not": q.mustNot} {
+		if len(qs) > 0 {
+			b[name] = querySources(qs)
+		}
+	}
+	if q.minimumShouldMatch != "" {
+		b["minimum_should_match"] = q.minimumShouldMatch
+	}
+	if q.boost != nil {
+		b["boost"] = *q.boost
+	}
+	return map[string]interface{}{"bool": b}
+}
+
+// TermQuery matches documents whose field holds exactly value.
+type TermQuery struct {
+	field string
+	value interface{}
+}
+
+func NewTermQuery(field string, value interface{}) *TermQuery {
+	return &TermQuery{field: field, value: value}
+}
+
+func (q *TermQuery) Source() map[string]interface{} {
+	return map[string]interface{}{"term": map[string]interface{}{q.field: q.value}}
+}
+
+// TermsQuery matches documents whose field holds any of values.
+type TermsQuery struct {
+	field  string
+	values []interface{}
+}
+
+func NewTermsQuery(field string, values ...interface{}) *TermsQuery {
+	return &TermsQuery{field: field, values: values}
+}
+
+func (q *TermsQuery) Source() map[string]interface{} {
+	values := q.values
+	if values == nil {
+		values = []interface{}{}
+	}
+	return map[string]interface{}{"terms": map[string]interface{}{q.field: values}}
+}
+
+// MatchQuery runs a full text match on one field.
+type MatchQuery struct {
+	field     string
+	text      string
+	operator  string
+	fuzziness string
+	analyzer  string
+}
+
+func NewMatchQuery(field, text string) *MatchQuery {
+	return &MatchQuery{field: field, text: text}
+}
+
+// Operator is "or" (the default) or "and".
+func (q *MatchQuery) Operator(op string) *MatchQuery {
+	q.operator = op
+	return q
+}
+
+// Fuzziness is an edit distance such as "AUTO" or "1".
+func (q *MatchQuery) Fuzziness(f string) *MatchQuery {
+	q.fuzziness = f
+	return q
+}
+
+func (q *MatchQuery) Analyzer(a string) *MatchQuery {
+	q.analyzer = a
+	return q
+}
+
+func (q *MatchQuery) Source() map[string]interface{} {
+	m := map[string]interface{}{"query": q.text}
+	if q.operator != "" {
+		m["operator"] = q.operator
+	}
+	if q.fuzziness != "" {
+		m["fuzziness"] = q.fuzziness
+	}
+	if q.analyzer != "" {
+		m["analyzer"] = q.analyzer
+	}
+	return map[string]interface{}{"match": map[string]interface{}{q.field: m}}
+}
+
+// MatchPhraseQuery matches the words of text in order.
+type MatchPhraseQuery struct {
+	field string
+	text  string
+	slop  *int
+}
+
+func NewMatchPhraseQuery(field, text string) *MatchPhraseQuery {
+	return &MatchPhraseQuery{field: field, text: text}
+}
+
+// Slop allows that many positions between the words.
+func (q *MatchPhraseQuery) Slop(n int) *MatchPhraseQuery {
+	q.slop = &n
+	return q
+}
+
+func (q *MatchPhraseQuery) Source() map[string]interface{} {
+	m := map[string]interface{}{"query": q.text}
+	if q.slop != nil {
+		m["slop"] = *q.slop
+	}
+	return map[string]interface{}{"match_phrase": map[string]interface{}{q.field: m}}
+}
+
+// MultiMatchQuery runs a match over several fields. Fields may carry a
+// boost, as in "title^2".
+type MultiMatchQuery struct {
+	text                string
+	fields              []string
+	typ                 string
+	operator            string
+	fuzziness           string
+	autoSynonymsPhrase  *bool
+	fuzzyTranspositions *bool
+}
+
+func NewMultiMatchQuery(text string, fields ...string) *MultiMatchQuery {
+	return &MultiMatchQuery{text: text, fields: fields}
+}
+
+// Type is one of best_fields, most_fields, cross_fields, phrase,
+// phrase_prefix or bool_prefix.
+func (q *MultiMatchQuery) Type(t string) *MultiMatchQuery {
+	q.typ = t
+	return q
+}
+
+func (q *MultiMatchQuery) Operator(op string) *MultiMatchQuery {
+	q.operator = op
+	return q
+}
+
+func (q *MultiMatchQuery) Fuzziness(f string) *MultiMatchQuery {
+	q.fuzziness = f
+	return q
+}
+
+// AutoGenerateSynonymsPhraseQuery turns multi-term synonyms into phrase
+// queries. It is on by default.
+func (q *MultiMatchQuery) AutoGenerateSynonymsPhraseQuery(b bool) *MultiMatchQuery {
+	q.autoSynonymsPhrase = &b
+	return q
+}
+
+// FuzzyTranspositions counts swapped adjacent characters as one edit. It is
+// on by default.
+func (q *MultiMatchQuery) FuzzyTranspositions(b bool) *MultiMatchQuery {
+	q.fuzzyTranspositions = &b
+	return q
+}
+
+func (q *MultiMatchQuery) Source() map[string]interface{} {
+	m := map[string]interface{}{"query": q.text}
+	if q.autoSynonymsPhrase != nil {
+		m["auto_generate_synonyms_phrase_query"] = *q.autoSynonymsPhrase
+	}
+	if q.fuzzyTranspositions != nil {
+		m["fuzzy_transpositions"] = *q.fuzzyTranspositions
+	}
+	if len(q.fields) > 0 {
+		m["fields"] = q.fields
+	}
+	if q.typ != "" {
+		m["type"] = q.typ
+	}
+	if q.operator != "" {
+		m["operator"] = q.operator
+	}
+	if q.fuzziness != "" {
+		m["fuzziness"] = q.fuzziness
+	}
+	return map[string]interface{}{"multi_match": m}
+}
+
+// RangeQuery matches field values within bounds. Bounds of date fields may
+// use date math such as "now-7d/d".
+type RangeQuery struct {
+	field  string
+	bounds map[string]interface{}
+}
+
+func NewRangeQuery(field string) *RangeQuery {
+	return &RangeQuery{field: field, bounds: map[string]interface{}{}}
+}
+
+func (q *RangeQuery) Gt(v interface{}) *RangeQuery  { q.bounds["gt"] = v; return q }
+func (q *RangeQuery) Gte(v interface{}) *RangeQuery { q.bounds["gte"] = v; return q }
+func (q *RangeQuery) Lt(v interface{}) *RangeQuery  { q.bounds["lt"] = v; return q }
+func (q *RangeQuery) Lte(v interface{}) *RangeQuery { q.bounds["lte"] = v; return q }
+
+// Format parses date bounds with f, e.g. "yyyy-MM-dd", instead of the
+// format of the field mapping.
+func (q *RangeQuery) Format(f string) *RangeQuery {
+	q.bounds["format"] = f
+	return q
+}
+
+// TimeZone converts date bounds and date math from tz, an offset such as
+// "+01:00" or a zone id such as "Europe/Berlin", to UTC.
+func (q *RangeQuery) TimeZone(tz string) *RangeQuery {
+	q.bounds["time_zone"] = tz
+	return q
+}
+
+// Range gives the bounds of a range as a value, for FilterQuery filters and
+// QueryByRange. Nil bounds are left open.
+type Range struct {
+	Gt, Gte, Lt, Lte interface{}
+	Format           string
+	TimeZone         string
+}
+
+// Query returns the range clause for field.
+func (rg Range) Query(field string) *RangeQuery {
+	q := NewRangeQuery(field)
+	if rg.Gt != nil {
+		q.Gt(rg.Gt)
+	}
+	if rg.Gte != nil {
+		q.Gte(rg.Gte)
+	}
+	if rg.Lt != nil {
+		q.Lt(rg.Lt)
+	}
+	if rg.Lte != nil {
+		q.Lte(rg.Lte)
+	}
+	if rg.Format != "" {
+		q.Format(rg.Format)
+	}
+	if rg.TimeZone != "" {
+		q.TimeZone(rg.TimeZone)
+	}
+	return q
+}
+
+func (q *RangeQuery) Source() map[string]interface{} {
+	return map[string]interface{}{"range": map[string]interface{}{q.field: q.bounds}}
+}
+
+// ExistsQuery matches documents with a value in field.
+type ExistsQuery struct {
+	field string
+}
+
+func NewExistsQuery(field string) *ExistsQuery {
+	return &ExistsQuery{field: field}
+}
+
+func (q *ExistsQuery) Source() map[string]interface{} {
+	return map[string]interface{}{"exists": map[string]interface{}{"field": q.field}}
+}
+
+// PrefixQuery matches terms starting with prefix.
+type PrefixQuery struct {
+	field, prefix string
+}
+
+func NewPrefixQuery(field, prefix string) *PrefixQuery {
+	return &PrefixQuery{field: field, prefix: prefix}
+}
+
+func (q *PrefixQuery) Source() map[string]interface{} {
+	return map[string]interface{}{"prefix": map[string]interface{}{q.field: map[string]interface{}{"value": q.prefix}}}
+}
+
+// WildcardQuery matches terms against a pattern with * and ?.
+type WildcardQuery struct {
+	field, pattern string
+}
+
+func NewWildcardQuery(field, pattern string) *WildcardQuery {
+	return &WildcardQuery{field: field, pattern: pattern}
+}
+
+func (q *WildcardQuery) Source() map[string]interface{} {
+	return map[string]interface{}{"wildcard": map[string]interface{}{q.field: map[string]interface{}{"value": q.pattern}}}
+}
+
+// RegexpQuery matches terms against a Lucene regular expression.
+type RegexpQuery struct {
+	field, pattern string
+}
+
+func NewRegexpQuery(field, pattern string) *RegexpQuery {
+	return &RegexpQuery{field: field, pattern: pattern}
+}
+
+func (q *RegexpQuery) Source() map[string]interface{} {
+	return map[string]interface{}{"regexp": map[string]interface{}{q.field: map[string]interface{}{"value": q.pattern}}}
+}
+
+// IdsQuery matches documents by id.
+type IdsQuery struct {
+	ids []string
+}
+
+func NewIdsQuery(ids ...string) *IdsQuery {
+	return &IdsQuery{ids: ids}
+}
+
+func (q *IdsQuery) Source() map[string]interface{} {
+	ids := q.ids
+	if ids == nil {
+		ids = []string{}
+	}
+	return map[string]interface{}{"ids": map[string]interface{}{"values": ids}}
+}
+
+// NestedQuery runs query against the nested objects under path.
+type NestedQuery struct {
+	path      string
+	query     Query
+	scoreMode string
+}
+
+func NewNestedQuery(path string, query Query) *NestedQuery {
+	return &NestedQuery{path: path, query: query}
+}
+
+// ScoreMode is one of avg (the default), max, min, sum or none.
+func (q *NestedQuery) ScoreMode(m string) *NestedQuery {
+	q.scoreMode = m
+	return q
+}
+
+func (q *NestedQuery) Source() map[string]interface{} {
+	m := map[string]interface{}{"path": q.path}
+	if q.query != nil {
+		m["query"] = q.query.Source()
+	} else {
+		m["query"] = NewMatchAllQuery().Source()
+	}
+	if q.scoreMode != "" {
+		m["score_mode"] = q.scoreMode
+	}
+	return map[string]interface{}{"nested": m}
+}
+
+// MatchAllQuery matches every document.
+type MatchAllQuery struct{}
+
+func NewMatchAllQuery() *MatchAllQuery { return &MatchAllQuery{} }
+
+func (q *MatchAllQuery) Source() map[string]interface{} {
+	return map[string]interface{}{"match_all": map[string]interface{}{}}
+}
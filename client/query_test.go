@@ -0,0 +1,71 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestQuerySource(t *testing.T) {
+	tests := []struct {
+		name string
+		q    Query
+		want string
+	}{
+		{"term", NewTermQuery("meal", "dinner"), `{"term":{"meal":"dinner"}}`},
+		{"terms", NewTermsQuery("labels", "vegan", "quick"), `{"terms":{"labels":["vegan","quick"]}}`},
+		{"match", NewMatchQuery("title", `apple "pie"`).Operator("and"), `{"match":{"title":{"operator":"and","query":"apple \"pie\""}}}`},
+		{"match_phrase", NewMatchPhraseQuery("title", "apple pie").Slop(1), `{"match_phrase":{"title":{"query":"apple pie","slop":1}}}`},
+		{"multi_match", NewMultiMatchQuery("apple", "title^2", "intro").Type("best_fields"), `{"multi_match":{"fields":["title^2","intro"],"query":"apple","type":"best_fields"}}`},
+		{"range", NewRangeQuery("serves").Gte(2).Lt(8), `{"range":{"serves":{"gte":2,"lt":8}}}`},
+		{"exists", NewExistsQuery("img"), `{"exists":{"field":"img"}}`},
+		{"prefix", NewPrefixQuery("title", "app"), `{"prefix":{"title":{"value":"app"}}}`},
+		{"wildcard", NewWildcardQuery("title", "ap*e"), `{"wildcard":{"title":{"value":"ap*e"}}}`},
+		{"regexp", NewRegexpQuery("title", "ap.+"), `{"regexp":{"title":{"value":"ap.+"}}}`},
+		{"ids", NewIdsQuery("1", "2"), `{"ids":{"values":["1","2"]}}`},
+		{"nested", NewNestedQuery("instructions", NewMatchQuery("instructions.steps", "bake")).ScoreMode("max"),
+			`{"nested":{"path":"instructions","query":{"match":{"instructions.steps":{"query":"bake"}}},"score_mode":"max"}}`},
+		{"bool", NewBoolQuery().
+			Must(NewMatchQuery("title", "pie")).
+			Should(NewTermQuery("labels", "vegan"), NewTermQuery("labels", "quick")).
+			Filter(NewTermQuery("user_id", 1)).
+			MustNot(NewExistsQuery("deleted")).
+			MinimumShouldMatch("1"),
+			`{"bool":{"filter":[{"term":{"user_id":1}}],"minimum_should_match":"1","must":[{"match":{"title":{"query":"pie"}}}],"must_not":[{"exists":{"field":"deleted"}}],"should":[{"term":{"labels":"vegan"}},{"term":{"labels":"quick"}}]}}`},
+		{"raw", RawQuery(`{"match_all":{"boost":2}}`), `{"match_all":{"boost":2}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(tt.q.Source())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.want {
+				t.Errorf("got  %s\nwant %s", b, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvalidRawQuery(t *testing.T) {
+	for _, src := range []string{`{"match_all":`, `[{"match_all":{}}]`, `null`, ``} {
+		if _, err := NewRawQuery(src); err == nil {
+			t.Errorf("NewRawQuery(%q) accepted", src)
+		}
+	}
+	if q, err := NewRawQuery(`{"match_all":{}}`); err != nil || string(q) != `{"match_all":{}}` {
+		t.Errorf("NewRawQuery = %s, %v", q, err)
+	}
+
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	})
+	q := NewBoolQuery().Filter(RawQuery(`{"term":{"meal":`))
+	if _, err := s.Search("recipe_data", q); err == nil || !strings.Contains(err.Error(), "invalid raw query") {
+		t.Errorf("Search: %v", err)
+	}
+	if _, err := s.Count("recipe_data", q); err == nil {
+		t.Error("Count sent an invalid raw query")
+	}
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestFilterQueryWithRanges(t *testing.T) {
+	var got string
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		var body struct {
+			Query json.RawMessage `json:"query"`
+		}
+		json.Unmarshal(b, &body)
+		got = string(body.Query)
+		w.Write([]byte(`{"hits":{"hits":[]}}`))
+	})
+
+	_, err := s.FilterQuery("recipe_data", map[string]interface{}{
+		"user_id": 1,
+		"serves":  Range{Gte: 2, Lt: 8},
+		"created": Range{Gte: "now-7d/d", Lte: "now/d", TimeZone: "Europe/Berlin"},
+		"cal":     NewRangeQuery("cal").Lte(500),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"bool":{"filter":[` +
+		`{"range":{"cal":{"lte":500}}},` +
+		`{"range":{"created":{"gte":"now-7d/d","lte":"now/d","time_zone":"Europe/Berlin"}}},` +
+		`{"range":{"serves":{"gte":2,"lt":8}}},` +
+		`{"term":{"user_id":1}}]}}`
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	_, err = s.QueryByRange("recipe_data", "created", Range{Gte: "01/01/2024", Format: "dd/MM/yyyy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"bool":{"filter":[{"range":{"created":{"format":"dd/MM/yyyy","gte":"01/01/2024"}}}]}}`; got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,270 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// ErrReindexCount is returned by Reindex when the new index does not hold
+// the documents the old one did. The alias is left in place.
+var ErrReindexCount = errors.New("reindex count mismatch")
+
+// ReindexConfig describes the index Reindex builds. Zero values take the
+// defaults noted on each field.
+type ReindexConfig struct {
+	// Options create the new index, typically WithMappingOf and the
+	// settings.
+	Options []IndexOption
+
+	// Query limits the documents copied; by default all are.
+	Query Query
+
+	// Script is a painless script run on each document, with Params. It may
+	// set ctx.op to "noop" to skip one.
+	Script string
+	Params map[string]interface{}
+
+	// DeleteOld deletes the old index once the alias moved.
+	DeleteOld bool
+
+	PollInterval time.Duration // how often the reindex task is checked, default 1s
+}
+
+// ReindexResult reports what Reindex did.
+type ReindexResult struct {
+	OldIndex string // "" when the alias did not exist
+	NewIndex string
+	Version  int
+	Copied   int64 // documents in the new index
+	Noops    int64 // documents the script skipped
+	Took     time.Duration
+	Deleted  bool // whether the old index was deleted
+}
+
+// Reindex rebuilds the index behind alias without interrupting searches. It
+// creates alias_v{N}, N one past the highest existing version, with
+// cfg.Options, copies the documents of the current index with _reindex,
+// checks the document counts and then moves alias to the new index in one
+// atomic step.
+//
+// If alias is still a plain index, as indices created before versioning
+// are, it is copied to alias_v1 and replaced by the alias; that removes the
+// plain index whatever DeleteOld says.
+//
+// Writes to alias while the copy runs go to the old index only, so pause
+// them or replay them afterwards. On failure the alias is not touched and
+// the new index is left for inspection.
+func (r *SearchEngine) Reindex(alias string, cfg ReindexConfig) (*ReindexResult, error) {
+	return r.ReindexCtx(context.Background(), alias, cfg)
+}
+
+// ReindexCtx is like Reindex but carries ctx to the request.
+func (r *SearchEngine) ReindexCtx(ctx context.Context, alias string, cfg ReindexConfig) (*ReindexResult, error) {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	start := time.Now()
+
+	old, err := r.AliasIndicesCtx(ctx, alias)
+	if err != nil {
+		return nil, err
+	}
+	if len(old) > 1 {
+		return nil, fmt.Errorf("reindex: alias %s points to %d indices", alias, len(old))
+	}
+	res := &ReindexResult{}
+	plain := false
+	if len(old) == 1 {
+		res.OldIndex = old[0]
+	} else if plain, err = r.IndexExistsCtx(ctx, alias); err != nil {
+		return nil, err
+	} else if plain {
+		res.OldIndex = alias
+	}
+
+	if res.Version, err = r.nextVersion(ctx, alias); err != nil {
+		return nil, err
+	}
+	res.NewIndex = fmt.Sprintf("%s_v%d", alias, res.Version)
+	if err := r.CreateIndexCtx(ctx, res.NewIndex, cfg.Options...); err != nil {
+		return nil, err
+	}
+
+	if res.OldIndex != "" {
+		want, err := r.CountCtx(ctx, res.OldIndex, cfg.Query)
+		if err != nil {
+			return nil, err
+		}
+		task, err := r.startReindex(ctx, res.OldIndex, res.NewIndex, cfg)
+		if err != nil {
+			return nil, err
+		}
+		status, err := r.waitTask(ctx, task, cfg.PollInterval)
+		if err != nil {
+			return nil, err
+		}
+		res.Noops = status.Noops
+		if res.Copied, err = r.CountCtx(ctx, res.NewIndex, nil); err != nil {
+			return nil, err
+		}
+		if res.Copied != want-res.Noops {
+			return res, fmt.Errorf("%w: %s holds %d documents, %s %d of which %d skipped",
+				ErrReindexCount, res.NewIndex, res.Copied, res.OldIndex, want, res.Noops)
+		}
+	}
+
+	actions := []*AliasAction{NewAddAlias(res.NewIndex, alias)}
+	switch {
+	case plain:
+		actions = append(actions, NewRemoveIndex(alias))
+	case res.OldIndex != "":
+		actions = append(actions, NewRemoveAlias(res.OldIndex, alias))
+	}
+	if err := r.UpdateAliasesCtx(ctx, actions...); err != nil {
+		return res, err
+	}
+	res.Deleted = plain
+
+	if cfg.DeleteOld && !plain && res.OldIndex != "" {
+		if _, err := r.DeleteIndexCtx(ctx, res.OldIndex); err != nil {
+			return res, err
+		}
+		res.Deleted = true
+	}
+	res.Took = time.Since(start)
+	return res, nil
+}
+
+// nextVersion returns one past the highest N of the alias_v{N} indices.
+func (r *SearchEngine) nextVersion(ctx context.Context, alias string) (int, error) {
+	req := esapi.CatIndicesRequest{
+		Index:  []string{alias + "_v*"},
+		H:      []string{"index"},
+		Format: "json",
+	}
+	res, err := req.Do(ctx, r.Client())
+	if err != nil {
+		return 0, fmt.Errorf("cat indices request: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return 0, newESError("cat indices", res)
+	}
+	var list []struct {
+		Index string `json:"index"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&list); err != nil {
+		return 0, fmt.Errorf("cat indices decode: %w", err)
+	}
+	max := 0
+	for _, l := range list {
+		if n, err := strconv.Atoi(strings.TrimPrefix(l.Index, alias+"_v")); err == nil && n > max {
+			max = n
+		}
+	}
+	return max + 1, nil
+}
+
+// reindexStatus is the outcome of a reindex task.
+type reindexStatus struct {
+	Total    int64             `json:"total"`
+	Created  int64             `json:"created"`
+	Noops    int64             `json:"noops"`
+	Failures []json.RawMessage `json:"failures"`
+}
+
+func (r *SearchEngine) startReindex(ctx context.Context, from, to string, cfg ReindexConfig) (string, error) {
+	source := map[string]interface{}{"index": from}
+	if cfg.Query != nil {
+		source["query"] = cfg.Query.Source()
+	}
+	body := map[string]interface{}{
+		"source": source,
+		"dest":   map[string]interface{}{"index": to},
+	}
+	if cfg.Script != "" {
+		script := map[string]interface{}{"source": cfg.Script, "lang": "painless"}
+		if len(cfg.Params) > 0 {
+			script["params"] = cfg.Params
+		}
+		body["script"] = script
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return "", fmt.Errorf("encode reindex: %w", err)
+	}
+	r.logRequest(ctx, "reindex", to, string(b))
+
+	wait, refresh := false, true
+	req := esapi.ReindexRequest{
+		Body:              strings.NewReader(string(b)),
+		WaitForCompletion: &wait,
+		Refresh:           &refresh,
+	}
+	res, err := req.Do(ctx, r.Client())
+	if err != nil {
+		return "", fmt.Errorf("reindex request: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return "", newESError("reindex", res)
+	}
+	var task struct {
+		Task string `json:"task"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&task); err != nil {
+		return "", fmt.Errorf("reindex decode: %w", err)
+	}
+	return task.Task, nil
+}
+
+// waitTask polls the reindex task until it completes.
+func (r *SearchEngine) waitTask(ctx context.Context, task string, every time.Duration) (*reindexStatus, error) {
+	for {
+		req := esapi.TasksGetRequest{TaskID: task}
+		res, err := req.Do(ctx, r.Client())
+		if err != nil {
+			return nil, fmt.Errorf("get task request: %w", err)
+		}
+		if res.IsError() {
+			err := newESError("get task", res)
+			res.Body.Close()
+			return nil, err
+		}
+		var body struct {
+			Completed bool            `json:"completed"`
+			Error     json.RawMessage `json:"error"`
+			Response  reindexStatus   `json:"response"`
+		}
+		err = json.NewDecoder(res.Body).Decode(&body)
+		res.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("get task decode: %w", err)
+		}
+		if body.Completed {
+			if len(body.Error) > 0 {
+				return nil, fmt.Errorf("reindex task %s failed: %s", task, body.Error)
+			}
+			if n := len(body.Response.Failures); n > 0 {
+				return nil, fmt.Errorf("reindex task %s: %d documents failed, first: %s", task, n, body.Response.Failures[0])
+			}
+			return &body.Response, nil
+		}
+		r.logf(ctx, LevelDebug, "reindex running", "task", task)
+
+		t := time.NewTimer(every)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
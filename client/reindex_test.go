@@ -0,0 +1,136 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeReindexCluster answers the requests of a Reindex of recipe_data. live
+// is the index the alias points to, or "" for a plain recipe_data index;
+// copied is the count of the new index.
+func fakeReindexCluster(t *testing.T, live string, copied string, aliases, deleted *string) http.HandlerFunc {
+	polls := 0
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/_alias/recipe_data":
+			if live == "" {
+				w.WriteHeader(404)
+				w.Write([]byte(`{"error":"alias [recipe_data] missing","status":404}`))
+				return
+			}
+			w.Write([]byte(`{"` + live + `":{"aliases":{"recipe_data":{}}}}`))
+		case r.Method == http.MethodHead && r.URL.Path == "/recipe_data":
+			w.WriteHeader(200)
+		case r.URL.Path == "/_cat/indices/recipe_data_v*":
+			if live == "" {
+				w.Write([]byte(`[]`))
+				return
+			}
+			w.Write([]byte(`[{"index":"recipe_data_v1"},{"index":"recipe_data_v3"},{"index":"recipe_data_vx"}]`))
+		case r.Method == http.MethodPut:
+			w.Write([]byte(`{"acknowledged":true}`))
+		case strings.HasSuffix(r.URL.Path, "/_count"):
+			if strings.HasPrefix(r.URL.Path, "/recipe_data_v4/") || r.URL.Path == "/recipe_data_v1/_count" && live == "" {
+				w.Write([]byte(`{"count":` + copied + `}`))
+				return
+			}
+			w.Write([]byte(`{"count":3}`))
+		case r.URL.Path == "/_reindex":
+			b, _ := io.ReadAll(r.Body)
+			if r.URL.Query().Get("wait_for_completion") != "false" || !strings.Contains(string(b), `"dest":{"index":"recipe_data_v`) {
+				t.Errorf("unexpected reindex %s %s", r.URL.RawQuery, b)
+			}
+			w.Write([]byte(`{"task":"node:7"}`))
+		case r.URL.Path == "/_tasks/node:7":
+			if polls++; polls == 1 {
+				w.Write([]byte(`{"completed":false,"task":{}}`))
+				return
+			}
+			w.Write([]byte(`{"completed":true,"response":{"total":3,"created":2,"noops":1,"failures":[]}}`))
+		case r.URL.Path == "/_aliases":
+			b, _ := io.ReadAll(r.Body)
+			*aliases = string(b)
+			w.Write([]byte(`{"acknowledged":true}`))
+		case r.Method == http.MethodDelete:
+			*deleted = r.URL.Path
+			w.Write([]byte(`{"acknowledged":true}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}
+}
+
+func TestReindexSwapsAlias(t *testing.T) {
+	var aliases, deleted string
+	s := newTestEngine(t, fakeReindexCluster(t, "recipe_data_v3", "2", &aliases, &deleted))
+
+	res, err := s.Reindex("recipe_data", ReindexConfig{
+		Script:       "if (ctx._source.draft) { ctx.op = 'noop' }",
+		DeleteOld:    true,
+		PollInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.OldIndex != "recipe_data_v3" || res.NewIndex != "recipe_data_v4" || res.Copied != 2 || res.Noops != 1 || !res.Deleted {
+		t.Errorf("unexpected result %+v", res)
+	}
+	want := `{"actions":[{"add":{"alias":"recipe_data","index":"recipe_data_v4"}},{"remove":{"alias":"recipe_data","index":"recipe_data_v3"}}]}`
+	if aliases != want {
+		t.Errorf("aliases\n got %s\nwant %s", aliases, want)
+	}
+	if deleted != "/recipe_data_v3" {
+		t.Errorf("old index not deleted: %q", deleted)
+	}
+}
+
+func TestReindexFromPlainIndex(t *testing.T) {
+	var aliases, deleted string
+	s := newTestEngine(t, fakeReindexCluster(t, "", "2", &aliases, &deleted))
+
+	res, err := s.Reindex("recipe_data", ReindexConfig{PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.OldIndex != "recipe_data" || res.NewIndex != "recipe_data_v1" || !res.Deleted {
+		t.Errorf("unexpected result %+v", res)
+	}
+	if !strings.Contains(aliases, `{"remove_index":{"index":"recipe_data"}}`) {
+		t.Errorf("plain index not replaced: %s", aliases)
+	}
+}
+
+func TestReindexCountMismatch(t *testing.T) {
+	var aliases, deleted string
+	s := newTestEngine(t, fakeReindexCluster(t, "recipe_data_v3", "1", &aliases, &deleted))
+
+	_, err := s.Reindex("recipe_data", ReindexConfig{DeleteOld: true, PollInterval: time.Millisecond})
+	if !errors.Is(err, ErrReindexCount) {
+		t.Fatalf("expected ErrReindexCount, got %v", err)
+	}
+	if aliases != "" || deleted != "" {
+		t.Errorf("nothing should change after a mismatch: %q %q", aliases, deleted)
+	}
+}
+
+func TestReindexFailedSwapKeepsPlainIndex(t *testing.T) {
+	var aliases, deleted string
+	h := fakeReindexCluster(t, "", "2", &aliases, &deleted)
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/_aliases" {
+			w.WriteHeader(400)
+			w.Write([]byte(`{"error":{"type":"illegal_state_exception","reason":"alias conflict"},"status":400}`))
+			return
+		}
+		h(w, r)
+	})
+
+	res, err := s.Reindex("recipe_data", ReindexConfig{PollInterval: time.Millisecond})
+	if err == nil || res == nil || res.Deleted {
+		t.Errorf("a failed swap must not report the plain index deleted: %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,229 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// Repository binds an index to the document type T. Documents are encoded
+// with ToJSON when *T implements SearchEngine_Doc and with encoding/json
+// otherwise; ids are read with GetID and written back with SetID when *T
+// has those methods.
+type Repository[T any] struct {
+	engine *SearchEngine
+	index  string
+}
+
+// Match is a search hit decoded into T. The embedded Hit carries the id,
+// index, score and raw source.
+type Match[T any] struct {
+	Hit
+	Doc *T
+}
+
+// NewRepository returns a Repository of T documents stored in indexName.
+func NewRepository[T any](engine *SearchEngine, indexName string) *Repository[T] {
+	return &Repository[T]{engine: engine, index: indexName}
+}
+
+// Index returns the name of the bound index.
+func (r *Repository[T]) Index() string {
+	return r.index
+}
+
+// Get returns the document stored under id.
+func (r *Repository[T]) Get(id string) (*T, error) {
+	return r.GetCtx(context.Background(), id)
+}
+
+// GetCtx is like Get but carries ctx to the request.
+func (r *Repository[T]) GetCtx(ctx context.Context, id string) (*T, error) {
+	hit, err := r.engine.GetOneCtx(ctx, r.index, id)
+	if err != nil {
+		return nil, err
+	}
+	return r.decode(*hit)
+}
+
+// Save indexes doc under its id, or under a generated id that is then set
+// on doc.
+func (r *Repository[T]) Save(doc *T) (id string, err error) {
+	return r.SaveCtx(context.Background(), doc)
+}
+
+// SaveCtx is like Save but carries ctx to the request.
+func (r *Repository[T]) SaveCtx(ctx context.Context, doc *T) (id string, err error) {
+	if doc == nil {
+		return "", fmt.Errorf("Empty doc")
+	}
+	body, err := encodeDoc(doc)
+	if err != nil {
+		return "", err
+	}
+	req := esapi.IndexRequest{
+		Index:      r.index,
+		DocumentID: docID(doc),
+		Body:       strings.NewReader(body),
+		Refresh:    "true",
+	}
+	res, err := req.Do(ctx, r.engine.Client())
+	if err != nil {
+		return "", fmt.Errorf("save doc request: %w", err)
+	}
+	defer res.Body.Close()
+	r.engine.logResponse(ctx, "save doc", res)
+
+	if res.IsError() {
+		return "", newESError("save doc", res)
+	}
+
+	var opt DocOpt
+	if err := json.NewDecoder(res.Body).Decode(&opt); err != nil {
+		return "", fmt.Errorf("save doc decode: %w", err)
+	}
+	setDocID(doc, opt.ID)
+	return opt.ID, nil
+}
+
+// SaveAll indexes docs with one bulk request and sets the generated ids on
+// them. Rejected documents are reported through a *BulkError.
+func (r *Repository[T]) SaveAll(docs []*T) error {
+	return r.SaveAllCtx(context.Background(), docs)
+}
+
+// SaveAllCtx is like SaveAll but carries ctx to the request.
+func (r *Repository[T]) SaveAllCtx(ctx context.Context, docs []*T) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	entries := make([]bulkEntry, 0, len(docs))
+	for _, doc := range docs {
+		body, err := encodeDoc(doc)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, newBulkEntry("index", r.index, docID(doc), body))
+	}
+	report, err := r.engine.executeBulk(ctx, "save all", r.index, "true", entries)
+	if report != nil {
+		for i, it := range report.Items {
+			if i < len(docs) && !it.Failed() {
+				setDocID(docs[i], it.ID)
+			}
+		}
+	}
+	return err
+}
+
+// Delete removes the document stored under id.
+func (r *Repository[T]) Delete(id string) error {
+	return r.DeleteCtx(context.Background(), id)
+}
+
+// DeleteCtx is like Delete but carries ctx to the request.
+func (r *Repository[T]) DeleteCtx(ctx context.Context, id string) error {
+	req := esapi.DeleteRequest{
+		Index:      r.index,
+		DocumentID: id,
+		Refresh:    "true",
+	}
+	res, err := req.Do(ctx, r.engine.Client())
+	if err != nil {
+		return fmt.Errorf("delete request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return newESError("delete doc", res)
+	}
+	return nil
+}
+
+// Search runs q and returns the hits with their score and metadata.
+func (r *Repository[T]) Search(q Query, opts ...SearchOption) ([]Match[T], error) {
+	return r.SearchCtx(context.Background(), q, opts...)
+}
+
+// SearchCtx is like Search but carries ctx to the request.
+func (r *Repository[T]) SearchCtx(ctx context.Context, q Query, opts ...SearchOption) ([]Match[T], error) {
+	res, err := r.engine.SearchCtx(ctx, r.index, q, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return r.matches(res.Hits)
+}
+
+// Find returns the documents whose fields equal the filters, as
+// FilterQuery does.
+func (r *Repository[T]) Find(filters map[string]interface{}, opts ...SearchOption) ([]T, error) {
+	return r.FindCtx(context.Background(), filters, opts...)
+}
+
+// FindCtx is like Find but carries ctx to the request.
+func (r *Repository[T]) FindCtx(ctx context.Context, filters map[string]interface{}, opts ...SearchOption) ([]T, error) {
+	res, err := r.engine.FilterQueryCtx(ctx, r.index, filters, opts...)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]T, 0, len(res.Hits))
+	for _, h := range res.Hits {
+		doc, err := r.decode(h)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, *doc)
+	}
+	return docs, nil
+}
+
+func (r *Repository[T]) matches(hits []Hit) ([]Match[T], error) {
+	l := make([]Match[T], 0, len(hits))
+	for _, h := range hits {
+		doc, err := r.decode(h)
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, Match[T]{Hit: h, Doc: doc})
+	}
+	return l, nil
+}
+
+func (r *Repository[T]) decode(h Hit) (*T, error) {
+	doc := new(T)
+	if err := json.Unmarshal(h.Source, doc); err != nil {
+		return nil, fmt.Errorf("decode %s/%s: %w", h.Index, h.ID, err)
+	}
+	setDocID(doc, h.ID)
+	return doc, nil
+}
+
+func encodeDoc(doc interface{}) (string, error) {
+	if d, ok := doc.(SearchEngine_Doc); ok {
+		if s := d.ToJSON(); s != "" {
+			return s, nil
+		}
+		return "", fmt.Errorf("encode doc: empty ToJSON of %T", doc)
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("encode doc: %w", err)
+	}
+	return string(b), nil
+}
+
+func docID(doc interface{}) string {
+	if d, ok := doc.(interface{ GetID() string }); ok {
+		return d.GetID()
+	}
+	return ""
+}
+
+func setDocID(doc interface{}, id string) {
+	if d, ok := doc.(interface{ SetID(string) }); ok {
+		d.SetID(id)
+	}
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type recipe struct {
+	ID    string `json:"-"`
+	Title string `json:"title"`
+}
+
+func (d *recipe) GetID() string   { return d.ID }
+func (d *recipe) SetID(id string) { d.ID = id }
+
+func TestRepositoryRoundTrip(t *testing.T) {
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/recipe_data/_doc":
+			b, _ := io.ReadAll(r.Body)
+			if string(b) != `{"title":"apple pie"}` {
+				t.Errorf("unexpected body %s", b)
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"_index":"recipe_data","_id":"gen-1","result":"created"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/recipe_data/_doc/gen-1":
+			w.Write([]byte(`{"_index":"recipe_data","_id":"gen-1","found":true,"_source":{"title":"apple pie"}}`))
+		case strings.HasSuffix(r.URL.Path, "/_search"):
+			w.Write([]byte(`{"took":1,"hits":{"total":{"value":1,"relation":"eq"},"hits":[
+				{"_index":"recipe_data","_id":"gen-1","_score":1.5,"_source":{"title":"apple pie"}}
+			]}}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+	repo := NewRepository[recipe](s, "recipe_data")
+
+	doc := &recipe{Title: "apple pie"}
+	if _, err := repo.Save(doc); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if doc.ID != "gen-1" {
+		t.Errorf("Save did not set the id, got %q", doc.ID)
+	}
+
+	got, err := repo.Get("gen-1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.ID != "gen-1" || got.Title != "apple pie" {
+		t.Errorf("Get: %+v", got)
+	}
+
+	matches, err := repo.Search(NewMatchAllQuery())
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Score != 1.5 || matches[0].Doc.ID != "gen-1" {
+		t.Errorf("Search: %+v", matches)
+	}
+}
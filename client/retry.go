@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy decides how often and how long to wait before a throttled or
+// temporarily failing request is sent again. Zero fields take the values of
+// DefaultRetryPolicy, except Jitter where zero means no jitter.
+type RetryPolicy struct {
+	MaxAttempts    int           // tries including the first one
+	InitialBackoff time.Duration // wait before the first retry, doubled on each further retry
+	MaxBackoff     time.Duration // upper bound of a single wait
+	Jitter         float64       // fraction of each wait that is randomized, 0 to 1
+	RetryOnStatus  []int         // HTTP statuses worth retrying
+}
+
+// DefaultRetryPolicy retries 429 and gateway errors up to three times.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+	Jitter:         0.2,
+	RetryOnStatus:  []int{429, 502, 503, 504},
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = DefaultRetryPolicy.InitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = DefaultRetryPolicy.MaxBackoff
+	}
+	if p.Jitter < 0 {
+		p.Jitter = 0
+	} else if p.Jitter > 1 {
+		p.Jitter = 1
+	}
+	if len(p.RetryOnStatus) == 0 {
+		p.RetryOnStatus = DefaultRetryPolicy.RetryOnStatus
+	}
+	return p
+}
+
+func (p *RetryPolicy) retryable(status int) bool {
+	for _, s := range p.RetryOnStatus {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the wait before retry number attempt, counting from 1.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		j := time.Duration(p.Jitter * float64(d))
+		d = d - j + time.Duration(rand.Int63n(int64(j)+1))
+	}
+	return d
+}
+
+// wait sleeps for the backoff of attempt, returning early with ctx's error.
+func (p *RetryPolicy) wait(ctx context.Context, attempt int) error {
+	t := time.NewTimer(p.backoff(attempt))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WithRetryPolicy retries requests answered with one of p's statuses, and
+// resubmits the bulk items rejected with one of them. With WithClient only
+// the bulk item retries apply, the client keeps its own transport settings.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(o *engineOptions) {
+		p = p.withDefaults()
+		o.retry = &p
+	}
+}
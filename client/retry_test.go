@@ -0,0 +1,99 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var fastRetry = WithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+
+func TestRetryOnTooManyRequests(t *testing.T) {
+	var requests int32
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"type":"es_rejected_execution_exception","reason":"rejected"},"status":429}`))
+			return
+		}
+		w.Write([]byte(`{"_index":"recipe_data","_id":"1","found":true,"_source":{"title":"apple pie"}}`))
+	}, fastRetry)
+
+	hit, err := s.GetOne("recipe_data", "1")
+	if err != nil {
+		t.Fatalf("GetOne: %v", err)
+	}
+	if hit.ID != "1" || requests != 2 {
+		t.Errorf("got id %q after %d requests", hit.ID, requests)
+	}
+}
+
+func TestBulkRetriesRejectedItems(t *testing.T) {
+	var bodies []string
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		var lines []string
+		sc := bufio.NewScanner(r.Body)
+		for sc.Scan() {
+			lines = append(lines, sc.Text())
+		}
+		bodies = append(bodies, strings.Join(lines, "\n"))
+		if len(bodies) == 1 {
+			w.Write([]byte(`{"took":1,"errors":true,"items":[
+				{"delete":{"_index":"recipe_data","_id":"1","result":"deleted","status":200}},
+				{"delete":{"_index":"recipe_data","_id":"2","status":429,"error":{"type":"es_rejected_execution_exception","reason":"rejected"}}},
+				{"delete":{"_index":"recipe_data","_id":"3","status":404,"result":"not_found"}}
+			]}`))
+			return
+		}
+		w.Write([]byte(`{"took":1,"errors":false,"items":[
+			{"delete":{"_index":"recipe_data","_id":"2","result":"deleted","status":200}}
+		]}`))
+	}, fastRetry)
+
+	err := s.BulkDelete("recipe_data", []string{"1", "2", "3"})
+	if len(bodies) != 2 {
+		t.Fatalf("expected 2 bulk requests, got %d", len(bodies))
+	}
+	if strings.Count(bodies[1], "\n") != 0 || !strings.Contains(bodies[1], `"_id":"2"`) {
+		t.Errorf("retry should only carry item 2, got %q", bodies[1])
+	}
+	be, ok := err.(*BulkError)
+	if !ok {
+		t.Fatalf("expected *BulkError for the missing document, got %v", err)
+	}
+	if failed := be.Report.Failed(); len(failed) != 1 || failed[0].ID != "3" {
+		t.Errorf("unexpected failures: %+v", failed)
+	}
+}
+
+func TestBulkRetryStopsWhenCtxEndsDuringBackoff(t *testing.T) {
+	var requests int32
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"took":1,"errors":true,"items":[
+			{"delete":{"_index":"recipe_data","_id":"1","status":429,"error":{"type":"es_rejected_execution_exception","reason":"rejected"}}}
+		]}`))
+	}, WithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := s.BulkDeleteCtx(ctx, "recipe_data", []string{"1"})
+	var be *BulkError
+	if !errors.As(err, &be) || len(be.Report.Failed()) != 1 {
+		t.Fatalf("expected *BulkError with the rejected item, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the ctx error, got %v", err)
+	}
+	if !errors.Is(err, ErrTooManyRequests) {
+		t.Errorf("expected ErrTooManyRequests, got %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("expected no retry after ctx ended, got %d requests", requests)
+	}
+}
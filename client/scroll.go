@@ -0,0 +1,165 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// Scroller walks every hit of a query with the scroll API, holding one
+// batch in memory at a time:
+//
+//	it := engine.Scroll("recipe_data", nil, WithSize(500))
+//	defer it.Close(ctx)
+//	for it.Next(ctx) {
+//		hit := it.Hit()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type Scroller struct {
+	engine    *SearchEngine
+	indexName string
+	query     Query
+	opts      *searchOptions
+
+	scrollID string
+	batch    []Hit
+	pos      int
+	total    TotalHits
+	started  bool
+	done     bool
+	err      error
+}
+
+// Scroll returns a Scroller over the hits of q in indexName. A nil q
+// matches all documents. WithSize sets the batch size and WithSource limits
+// the returned fields; WithFrom does not apply. Without WithSort hits come in
+// index order. No request is sent before the first call to Next.
+func (r *SearchEngine) Scroll(indexName string, q Query, opts ...SearchOption) *Scroller {
+	o := newSearchOptions(opts)
+	if o.keepAlive <= 0 {
+		o.keepAlive = time.Minute
+	}
+	return &Scroller{engine: r, indexName: indexName, query: q, opts: o}
+}
+
+// Next advances to the next hit, fetching a new batch when the current one
+// is used up. It returns false at the end or on error; the scroll context is
+// cleared in both cases.
+func (s *Scroller) Next(ctx context.Context) bool {
+	if s.done {
+		return false
+	}
+	s.pos++
+	if s.pos < len(s.batch) {
+		return true
+	}
+	res, err := s.fetch(ctx)
+	if err != nil {
+		s.err = err
+		s.finish(ctx)
+		return false
+	}
+	if res.ScrollID != "" {
+		s.scrollID = res.ScrollID
+	}
+	s.batch, s.pos = res.Hits, 0
+	if len(s.batch) == 0 {
+		s.finish(ctx)
+		return false
+	}
+	return true
+}
+
+func (s *Scroller) fetch(ctx context.Context) (*SearchResult, error) {
+	if !s.started {
+		s.started = true
+		body, err := searchBody(s.query)
+		if err != nil {
+			return nil, err
+		}
+		if body, err = s.opts.merge(body); err != nil {
+			return nil, err
+		}
+		req := esapi.SearchRequest{
+			Index:          []string{s.indexName},
+			Body:           strings.NewReader(body),
+			Size:           &s.opts.size,
+			Scroll:         s.opts.keepAlive,
+			TrackTotalHits: s.opts.trackTotalHits,
+		}
+		if _, ok := s.opts.body["sort"]; !ok {
+			// index order is the cheapest order to scroll in
+			req.Sort = []string{"_doc"}
+		}
+		res, err := s.engine.doSearch(ctx, req, s.indexName, body)
+		if err == nil {
+			s.total = res.Total
+		}
+		return res, err
+	}
+	b, err := json.Marshal(map[string]string{
+		"scroll":    fmt.Sprintf("%dms", s.opts.keepAlive.Milliseconds()),
+		"scroll_id": s.scrollID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req := esapi.ScrollRequest{Body: strings.NewReader(string(b))}
+	return s.engine.doSearch(ctx, req, s.indexName, string(b))
+}
+
+// Hit returns the current hit.
+func (s *Scroller) Hit() Hit {
+	if s.pos < len(s.batch) {
+		return s.batch[s.pos]
+	}
+	return Hit{}
+}
+
+// Total returns the number of matching documents, known after the first
+// call to Next.
+func (s *Scroller) Total() TotalHits {
+	return s.total
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (s *Scroller) Err() error {
+	return s.err
+}
+
+// Close clears the scroll context when the iteration stopped early. It is
+// safe to call more than once.
+func (s *Scroller) Close(ctx context.Context) error {
+	s.done = true
+	s.batch = nil
+	if s.scrollID == "" {
+		return nil
+	}
+	b, _ := json.Marshal(map[string][]string{"scroll_id": {s.scrollID}})
+	s.scrollID = ""
+	req := esapi.ClearScrollRequest{Body: strings.NewReader(string(b))}
+	res, err := req.Do(ctx, s.engine.Client())
+	if err != nil {
+		return fmt.Errorf("clear scroll request: %w", err)
+	}
+	defer res.Body.Close()
+	// the context may have expired already
+	if res.IsError() && res.StatusCode != 404 {
+		return newESError("clear scroll", res)
+	}
+	return nil
+}
+
+// finish ends the iteration, keeping the first error.
+func (s *Scroller) finish(ctx context.Context) {
+	if err := s.Close(ctx); err != nil && s.err == nil {
+		s.err = err
+	}
+}
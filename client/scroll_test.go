@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestScrollerWalksAllBatches(t *testing.T) {
+	var scrolls int
+	var cleared string
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/recipe_data/_search":
+			if r.URL.Query().Get("scroll") == "" {
+				t.Errorf("unexpected params %s", r.URL.RawQuery)
+			}
+			if b, _ := io.ReadAll(r.Body); !strings.Contains(string(b), `"_source":{"includes":["title"]}`) {
+				t.Errorf("source filter missing from %s", b)
+			}
+			w.Write([]byte(`{"_scroll_id":"s1","hits":{"total":{"value":3,"relation":"eq"},"hits":[{"_id":"1"},{"_id":"2"}]}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/_search/scroll":
+			scrolls++
+			if scrolls == 1 {
+				w.Write([]byte(`{"_scroll_id":"s2","hits":{"hits":[{"_id":"3"}]}}`))
+				return
+			}
+			w.Write([]byte(`{"_scroll_id":"s2","hits":{"hits":[]}}`))
+		case r.Method == http.MethodDelete && r.URL.Path == "/_search/scroll":
+			b, _ := io.ReadAll(r.Body)
+			cleared = string(b)
+			w.Write([]byte(`{"succeeded":true,"num_freed":1}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+	ctx := context.Background()
+
+	it := s.Scroll("recipe_data", NewMatchAllQuery(), WithSize(2), WithSource([]string{"title"}, nil))
+	defer it.Close(ctx)
+	var ids []string
+	for it.Next(ctx) {
+		ids = append(ids, it.Hit().ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 3 || ids[2] != "3" || it.Total().Value != 3 {
+		t.Errorf("got ids %v, total %+v", ids, it.Total())
+	}
+	if cleared != `{"scroll_id":["s2"]}` {
+		t.Errorf("scroll not cleared, got %q", cleared)
+	}
+}
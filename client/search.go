@@ -0,0 +1,209 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SearchOption adjusts a search request built by Search.
+type SearchOption func(*searchOptions)
+
+type searchOptions struct {
+	from           int
+	size           int
+	trackTotalHits interface{}
+	keepAlive      time.Duration
+
+	// body holds top level request body keys besides "query", such as
+	// "aggs"; they are merged into the body of every query method.
+	body map[string]interface{}
+}
+
+func (o *searchOptions) set(key string, v interface{}) {
+	if o.body == nil {
+		o.body = map[string]interface{}{}
+	}
+	o.body[key] = v
+}
+
+// merge adds the body options to the request body q.
+func (o *searchOptions) merge(q string) (string, error) {
+	if len(o.body) == 0 {
+		return q, nil
+	}
+	m := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(q), &m); err != nil {
+		return "", fmt.Errorf("decode query: %w", err)
+	}
+	for k, v := range o.body {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("encode %s: %w", k, err)
+		}
+		m[k] = b
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", fmt.Errorf("encode query: %w", err)
+	}
+	return string(b), nil
+}
+
+func newSearchOptions(opts []SearchOption) *searchOptions {
+	o := &searchOptions{from: 0, size: 100}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithFrom skips the first n hits.
+func WithFrom(n int) SearchOption {
+	return func(o *searchOptions) {
+		o.from = n
+	}
+}
+
+// WithSize returns at most n hits. The default is 100.
+func WithSize(n int) SearchOption {
+	return func(o *searchOptions) {
+		o.size = n
+	}
+}
+
+// WithTrackTotalHits makes the cluster count all matching documents instead
+// of stopping at 10,000, or skip counting when track is false.
+func WithTrackTotalHits(track bool) SearchOption {
+	return func(o *searchOptions) {
+		o.trackTotalHits = track
+	}
+}
+
+// WithTrackTotalHitsUpTo counts matching documents accurately up to n.
+func WithTrackTotalHitsUpTo(n int) SearchOption {
+	return func(o *searchOptions) {
+		o.trackTotalHits = n
+	}
+}
+
+// WithSource limits the returned _source to the includes fields, minus the
+// excludes fields. Both accept wildcards such as "instructions.*".
+func WithSource(includes, excludes []string) SearchOption {
+	return func(o *searchOptions) {
+		src := map[string][]string{}
+		if len(includes) > 0 {
+			src["includes"] = includes
+		}
+		if len(excludes) > 0 {
+			src["excludes"] = excludes
+		}
+		o.set("_source", src)
+	}
+}
+
+// WithoutSource leaves _source out of the hits, for searches that only need
+// the ids or the values asked for with WithFields.
+func WithoutSource() SearchOption {
+	return func(o *searchOptions) {
+		o.set("_source", false)
+	}
+}
+
+// WithFields returns the named fields, as the mapping formats them, in
+// Hit.Fields. Names accept wildcards; they also cover runtime fields.
+func WithFields(names ...string) SearchOption {
+	return func(o *searchOptions) {
+		o.set("fields", names)
+	}
+}
+
+// WithDocValueFields returns the doc values of the named fields in
+// Hit.Fields, which is cheap for keyword, numeric and date fields.
+func WithDocValueFields(names ...string) SearchOption {
+	return func(o *searchOptions) {
+		o.set("docvalue_fields", names)
+	}
+}
+
+// WithStoredFields returns the named fields mapped with "store": true in
+// Hit.Fields. Unless _source is also asked for, it is left out.
+func WithStoredFields(names ...string) SearchOption {
+	return func(o *searchOptions) {
+		o.set("stored_fields", names)
+	}
+}
+
+// WithScriptField computes a field of each hit with a painless script and
+// returns it in Hit.Fields under name. It may be given more than once.
+func WithScriptField(name, script string, params map[string]interface{}) SearchOption {
+	return func(o *searchOptions) {
+		sc := map[string]interface{}{"source": script}
+		if len(params) > 0 {
+			sc["params"] = params
+		}
+		fields, _ := o.body["script_fields"].(map[string]interface{})
+		if fields == nil {
+			fields = map[string]interface{}{}
+			o.set("script_fields", fields)
+		}
+		fields[name] = map[string]interface{}{"script": sc}
+	}
+}
+
+// WithKeepAlive sets how long a scroll context stays open between batches.
+// The default is one minute.
+func WithKeepAlive(d time.Duration) SearchOption {
+	return func(o *searchOptions) {
+		o.keepAlive = d
+	}
+}
+
+// TotalHits is the number of matching documents. Relation is "eq" when
+// Value is exact and "gte" when it is a lower bound.
+type TotalHits struct {
+	Value    int64  `json:"value"`
+	Relation string `json:"relation"`
+}
+
+// SearchResult is the response of a search.
+type SearchResult struct {
+	Took     int // milliseconds
+	TimedOut bool
+	Shards   Shard
+	Total    TotalHits
+	MaxScore float32
+	Hits     []Hit
+	PitID    string // latest point-in-time id, for searches on a PIT
+	ScrollID string // scroll context, for scroll searches
+
+	Aggregations Aggregations
+}
+
+// searchBody encodes q as a search request body. A nil q matches all
+// documents.
+func searchBody(q Query) (string, error) {
+	if q == nil {
+		q = NewMatchAllQuery()
+	}
+	b, err := json.Marshal(map[string]interface{}{"query": q.Source()})
+	if err != nil {
+		return "", fmt.Errorf("encode query: %w", err)
+	}
+	return string(b), nil
+}
+
+// Search runs q against indexName.
+func (r *SearchEngine) Search(indexName string, q Query, opts ...SearchOption) (*SearchResult, error) {
+	return r.SearchCtx(context.Background(), indexName, q, opts...)
+}
+
+// SearchCtx is like Search but carries ctx to the request.
+func (r *SearchEngine) SearchCtx(ctx context.Context, indexName string, q Query, opts ...SearchOption) (*SearchResult, error) {
+	body, err := searchBody(q)
+	if err != nil {
+		return nil, err
+	}
+	return r.executeQuery(ctx, indexName, body, newSearchOptions(opts))
+}
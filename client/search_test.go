@@ -0,0 +1,69 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestSearchPagingAndTotal(t *testing.T) {
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("from") != "20" || q.Get("size") != "10" || q.Get("track_total_hits") != "true" {
+			t.Errorf("unexpected params %s", r.URL.RawQuery)
+		}
+		w.Write([]byte(`{"took":4,"timed_out":false,"_shards":{"total":1,"successful":1,"skipped":0,"failed":0},
+			"hits":{"total":{"value":12345,"relation":"eq"},"max_score":2.5,"hits":[
+				{"_index":"recipe_data","_id":"21","_score":2.5,"_source":{"title":"apple pie"}}
+			]}}`))
+	})
+
+	res, err := s.FilterQuery("recipe_data", map[string]interface{}{"meal": "dessert"},
+		WithFrom(20), WithSize(10), WithTrackTotalHits(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Total != (TotalHits{Value: 12345, Relation: "eq"}) || res.Took != 4 || res.MaxScore != 2.5 || res.Shards.Successful != 1 {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	if len(res.Hits) != 1 || res.Hits[0].ID != "21" {
+		t.Errorf("unexpected hits: %+v", res.Hits)
+	}
+}
+
+func TestSearchReturnsFields(t *testing.T) {
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		want := `{"_source":false,"docvalue_fields":["created"],"fields":["title","tags"],"query":{"match_all":{}},` +
+			`"script_fields":{"steps":{"script":{"params":{"n":1},"source":"params._source.instructions.size() + params.n"}}}}`
+		if string(b) != want {
+			t.Errorf("body\n got %s\nwant %s", b, want)
+		}
+		w.Write([]byte(`{"hits":{"hits":[{"_id":"1","fields":{"title":["apple pie"],"tags":["sweet","baked"],"created":[1704067200000],"steps":[4]}}]}}`))
+	})
+
+	res, err := s.Search("recipe_data", nil, WithoutSource(), WithFields("title", "tags"), WithDocValueFields("created"),
+		WithScriptField("steps", "params._source.instructions.size() + params.n", map[string]interface{}{"n": 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := res.Hits[0].Fields
+	if len(f["tags"]) != 2 || f["title"][0] != "apple pie" || f["steps"][0] != float64(4) || res.Hits[0].Source != nil {
+		t.Errorf("unexpected fields: %+v", res.Hits[0])
+	}
+}
+
+func TestHitSortKeepsLongs(t *testing.T) {
+	var h Hit
+	err := json.Unmarshal([]byte(`{"_id":"1","sort":[1704067200000123457,"apple",null],"fields":{"serves":[4]}}`), &h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.ID != "1" || len(h.Sort) != 3 || h.Sort[0] != json.Number("1704067200000123457") || h.Sort[1] != "apple" || h.Sort[2] != nil {
+		t.Errorf("unexpected sort values %#v", h.Sort)
+	}
+	if h.Fields["serves"][0] != float64(4) {
+		t.Errorf("fields should decode as float64, got %#v", h.Fields)
+	}
+}
@@ -0,0 +1,169 @@
+package client
+
+// Sorter is one clause of the sort array of a search.
+type Sorter interface {
+	Source() interface{}
+}
+
+// WithSort orders the hits by sorts, in priority order. The sort values of
+// each hit are returned in Hit.Sort, ready to be used for search_after.
+func WithSort(sorts ...Sorter) SearchOption {
+	return func(o *searchOptions) {
+		l, _ := o.body["sort"].([]interface{})
+		o.set("sort", append(l, sortSources(sorts)...))
+	}
+}
+
+// WithSearchAfter starts after the hit with the given sort values, as taken
+// from Hit.Sort of the last hit of the previous page. It needs WithSort.
+func WithSearchAfter(values ...interface{}) SearchOption {
+	return func(o *searchOptions) {
+		o.set("search_after", values)
+	}
+}
+
+func sortSources(sorts []Sorter) []interface{} {
+	l := make([]interface{}, 0, len(sorts))
+	for _, s := range sorts {
+		l = append(l, s.Source())
+	}
+	return l
+}
+
+// FieldSort orders by the values of a field, ascending by default.
+type FieldSort struct {
+	field        string
+	order        string
+	missing      interface{}
+	unmappedType string
+	mode         string
+}
+
+func NewFieldSort(field string) *FieldSort {
+	return &FieldSort{field: field}
+}
+
+func (s *FieldSort) Asc() *FieldSort  { s.order = "asc"; return s }
+func (s *FieldSort) Desc() *FieldSort { s.order = "desc"; return s }
+
+// MissingFirst and MissingLast place documents without the field.
+func (s *FieldSort) MissingFirst() *FieldSort { s.missing = "_first"; return s }
+func (s *FieldSort) MissingLast() *FieldSort  { s.missing = "_last"; return s }
+
+// UnmappedType is the type assumed in indices where the field is not mapped,
+// so that sorting across several indices does not fail.
+func (s *FieldSort) UnmappedType(t string) *FieldSort {
+	s.unmappedType = t
+	return s
+}
+
+// Mode picks the value of multi-valued fields: min, max, sum, avg or median.
+func (s *FieldSort) Mode(m string) *FieldSort {
+	s.mode = m
+	return s
+}
+
+func (s *FieldSort) Source() interface{} {
+	m := map[string]interface{}{}
+	if s.order != "" {
+		m["order"] = s.order
+	}
+	if s.missing != nil {
+		m["missing"] = s.missing
+	}
+	if s.unmappedType != "" {
+		m["unmapped_type"] = s.unmappedType
+	}
+	if s.mode != "" {
+		m["mode"] = s.mode
+	}
+	return map[string]interface{}{s.field: m}
+}
+
+// ScoreSort orders by relevance, descending by default.
+type ScoreSort struct {
+	order string
+}
+
+func NewScoreSort() *ScoreSort {
+	return &ScoreSort{}
+}
+
+func (s *ScoreSort) Asc() *ScoreSort  { s.order = "asc"; return s }
+func (s *ScoreSort) Desc() *ScoreSort { s.order = "desc"; return s }
+
+func (s *ScoreSort) Source() interface{} {
+	if s.order == "" {
+		return "_score"
+	}
+	return map[string]interface{}{"_score": map[string]string{"order": s.order}}
+}
+
+// GeoDistanceSort orders by the distance of a geo_point field to a point,
+// nearest first by default.
+type GeoDistanceSort struct {
+	field    string
+	lat, lon float64
+	order    string
+	unit     string
+}
+
+func NewGeoDistanceSort(field string, lat, lon float64) *GeoDistanceSort {
+	return &GeoDistanceSort{field: field, lat: lat, lon: lon}
+}
+
+func (s *GeoDistanceSort) Asc() *GeoDistanceSort  { s.order = "asc"; return s }
+func (s *GeoDistanceSort) Desc() *GeoDistanceSort { s.order = "desc"; return s }
+
+// Unit of the returned sort value, such as "km" or "mi". The default is "m".
+func (s *GeoDistanceSort) Unit(u string) *GeoDistanceSort {
+	s.unit = u
+	return s
+}
+
+func (s *GeoDistanceSort) Source() interface{} {
+	m := map[string]interface{}{
+		s.field: map[string]float64{"lat": s.lat, "lon": s.lon},
+	}
+	if s.order != "" {
+		m["order"] = s.order
+	}
+	if s.unit != "" {
+		m["unit"] = s.unit
+	}
+	return map[string]interface{}{"_geo_distance": m}
+}
+
+// ScriptSort orders by the value a painless script computes per document.
+type ScriptSort struct {
+	script string
+	typ    string
+	params map[string]interface{}
+	order  string
+}
+
+// NewScriptSort sorts by script, whose result is of typ "number" or
+// "string".
+func NewScriptSort(script, typ string) *ScriptSort {
+	return &ScriptSort{script: script, typ: typ}
+}
+
+func (s *ScriptSort) Params(p map[string]interface{}) *ScriptSort {
+	s.params = p
+	return s
+}
+
+func (s *ScriptSort) Asc() *ScriptSort  { s.order = "asc"; return s }
+func (s *ScriptSort) Desc() *ScriptSort { s.order = "desc"; return s }
+
+func (s *ScriptSort) Source() interface{} {
+	script := map[string]interface{}{"source": s.script}
+	if s.params != nil {
+		script["params"] = s.params
+	}
+	m := map[string]interface{}{"type": s.typ, "script": script}
+	if s.order != "" {
+		m["order"] = s.order
+	}
+	return map[string]interface{}{"_script": m}
+}
@@ -0,0 +1,44 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestSortAndSearchAfter(t *testing.T) {
+	s := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		var body struct {
+			Sort        json.RawMessage `json:"sort"`
+			SearchAfter json.RawMessage `json:"search_after"`
+		}
+		json.Unmarshal(b, &body)
+		want := `[{"created":{"missing":"_last","order":"desc","unmapped_type":"date"}},"_score",` +
+			`{"_geo_distance":{"location":{"lat":52.5,"lon":13.4},"order":"asc","unit":"km"}},` +
+			`{"_script":{"order":"desc","script":{"params":{"f":2},"source":"doc['serves'].value * params.f"},"type":"number"}}]`
+		if string(body.Sort) != want {
+			t.Errorf("sort\ngot  %s\nwant %s", body.Sort, want)
+		}
+		if string(body.SearchAfter) != `[1700000000000,"42"]` {
+			t.Errorf("search_after %s", body.SearchAfter)
+		}
+		w.Write([]byte(`{"hits":{"hits":[{"_id":"43","_source":{},"sort":[1690000000000,"43"]}]}}`))
+	})
+
+	res, err := s.FilterQuery("recipe_data", map[string]interface{}{"meal": "dinner"},
+		WithSort(
+			NewFieldSort("created").Desc().MissingLast().UnmappedType("date"),
+			NewScoreSort(),
+			NewGeoDistanceSort("location", 52.5, 13.4).Asc().Unit("km"),
+			NewScriptSort("doc['serves'].value * params.f", "number").Params(map[string]interface{}{"f": 2}).Desc(),
+		),
+		WithSearchAfter(1700000000000, "42"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Hits) != 1 || len(res.Hits[0].Sort) != 2 || res.Hits[0].Sort[1] != "43" {
+		t.Errorf("unexpected hits %+v", res.Hits)
+	}
+}
@@ -14,18 +14,18 @@ var (
 )
 
 func init() {
-	e := client.InitESClient([]string{"http://127.0.0.1:9200"})
+	var e error
+	s, e = client.NewSearchEngine(client.WithAddresses("http://127.0.0.1:9200"))
 	if e != nil {
 		log.Fatal("Initing Client Error: ", e)
 	}
-	s = &client.SearchEngine{}
 	indexName = "recipe_data"
 }
 
 func TestSearchEngine(t *testing.T) {
 
 	//create an index
-	err := s.Index(indexName)
+	err := s.Index(indexName, client.WithShards(1), client.WithReplicas(0), client.WithMappingOf(CardRender{}))
 	if err != nil {
 		t.Errorf("Creating Index error:%v", err)
 	}
@@ -78,9 +78,9 @@ func TestSearchEngine(t *testing.T) {
 	//filter by fields
 	results, err := s.FilterQuery(indexName, map[string]interface{}{"user_id": 1})
 	if err != nil {
-		t.Errorf("Error in filter query:%v", err)
+		t.Fatalf("Error in filter query:%v", err)
 	}
-	if len(results) == 0 {
+	if len(results.Hits) == 0 {
 		t.Errorf("Error in filtering:%v", results)
 	}
 
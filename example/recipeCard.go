@@ -9,20 +9,20 @@ import (
 )
 
 type CardRender struct {
-	ID string `json:"id"`
+	ID string `json:"id" es:"-"`
 	// MGID      primitive.ObjectID `bson:"id" json:"mgid"`
-	CreatorID int32 `bson:"user_id" json:"user_id"`
+	CreatorID int32 `bson:"user_id" json:"user_id" es:"keyword"`
 
 	/*------ visible information ------*/
-	Title        string           `bson:"title" json:"title"`
-	Subheader    string           `bson:"subheader" json:"subheader"`
-	Story        string           `json:"intro" bson:"intro"`
+	Title        string           `bson:"title" json:"title" es:"type=text,analyzer=english,fields.raw=keyword"`
+	Subheader    string           `bson:"subheader" json:"subheader" es:"type=text,analyzer=english"`
+	Story        string           `json:"intro" bson:"intro" es:"type=text,analyzer=english"`
 	Sign         string           `json:"sign" bson:"sign"`
 	Serves       int8             `json:"serves" bson:"serves"`
-	Img          string           `bson:"img" json:"img"`
-	CoverImg     string           `bson:"coverImg" json:"coverImg"`
-	Instructions []InstructRender `json:"instructions"`
-	Meal         string           `json:"meal" bson:"meal"`
+	Img          string           `bson:"img" json:"img" es:"keyword,index=false"`
+	CoverImg     string           `bson:"coverImg" json:"coverImg" es:"keyword,index=false"`
+	Instructions []InstructRender `json:"instructions" es:"nested"`
+	Meal         string           `json:"meal" bson:"meal" es:"keyword"`
 
 	// Ingredients  []string         `json:"ingredients" bson:"ingredients"`
 	// Steps        []string         `json:"steps" bson:"steps"`
@@ -30,17 +30,17 @@ type CardRender struct {
 
 	/*------ visible information ------*/
 
-	Lang     string   `json:"lang" bson:"lang"`
-	Cal      string   `json:"cal" bson:"cal"`
-	Labels   []string `json:"labels" bson:"labels"`
-	Category string   `json:"category" bson:"category"`
-	Template string   `json:"template"`
+	Lang     string   `json:"lang" bson:"lang" es:"keyword"`
+	Cal      string   `json:"cal" bson:"cal" es:"type=integer"` // numeric strings, coerced on index
+	Labels   []string `json:"labels" bson:"labels" es:"keyword"`
+	Category string   `json:"category" bson:"category" es:"keyword"`
+	Template string   `json:"template" es:"keyword,index=false"`
 }
 
 type InstructRender struct {
-	GroupTitle string   `json:"group_title"`
-	Ingredient []string `json:"ingredients"`
-	Steps      []string `json:"steps"`
+	GroupTitle string   `json:"group_title" es:"type=text,analyzer=english"`
+	Ingredient []string `json:"ingredients" es:"type=text,analyzer=english"`
+	Steps      []string `json:"steps" es:"type=text,analyzer=english"`
 }
 
 func (r CardRender) ToJSON() string {
@@ -56,7 +56,7 @@ func (r CardRender) ToJSON() string {
 	return string(data)
 }
 
-func (r CardRender) SetID(id string) {
+func (r *CardRender) SetID(id string) {
 	r.ID = id
 }
 
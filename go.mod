@@ -1,12 +1,14 @@
 module github.com/go-kitchen/esearch-client-go
 
-go 1.19
+go 1.21
 
-require github.com/elastic/go-elasticsearch/v8 v8.14.0
+require (
+	github.com/elastic/go-elasticsearch/v8 v8.14.0
+	github.com/go-logr/logr v1.4.1
+)
 
 require (
 	github.com/elastic/elastic-transport-go/v8 v8.6.0 // indirect
-	github.com/go-logr/logr v1.4.1 // indirect
 	github.com/go-logr/stdr v1.2.2 // indirect
 	go.opentelemetry.io/otel v1.24.0 // indirect
 	go.opentelemetry.io/otel/metric v1.24.0 // indirect